@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"oc-data-be-challenge/internal/client"
+	"oc-data-be-challenge/internal/dedup"
+	"oc-data-be-challenge/internal/discard"
+	"oc-data-be-challenge/internal/usecase"
+	"time"
+)
+
+// NewDataSources creates the data sources configured by cfg.DataServerClient, in configuration order.
+func NewDataSources(cfg Config) ([]usecase.DataSource, error) {
+	sources := make([]usecase.DataSource, 0, len(cfg.DataServerClient))
+	for _, sourceCfg := range cfg.DataServerClient {
+		discardRules, err := discard.NewEngine(sourceCfg.DiscardRules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create discard rules of data source %q: %w", sourceCfg.Name, err)
+		}
+
+		timeUnit, err := client.ParseTimeUnit(sourceCfg.TimeUnit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time unit of data source %q: %w", sourceCfg.Name, err)
+		}
+
+		encoding := sourceCfg.ValueEncoding
+		valueCodec, err := client.NewValueCodec(client.ValueEncoding(encoding.Type), encoding.ByteOrder, encoding.Scale, encoding.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value encoding of data source %q: %w", sourceCfg.Name, err)
+		}
+
+		httpClient := &http.Client{Timeout: time.Millisecond * time.Duration(sourceCfg.TimeoutMs)}
+		decode := client.DecodeOptions{TimeUnit: timeUnit, ValueCodec: valueCodec}
+		sources = append(sources, usecase.DataSource{
+			Name:         sourceCfg.Name,
+			Client:       client.NewDataServerClient(sourceCfg.Name, sourceCfg.Host, httpClient, NewRetryPolicy(sourceCfg), NewCircuitBreaker(sourceCfg), decode),
+			DiscardRules: discardRules,
+			PollInterval: time.Millisecond * time.Duration(sourceCfg.PollIntervalMs),
+			Batch:        sourceCfg.Batch,
+		})
+	}
+	return sources, nil
+}
+
+// NewRetryPolicy creates the retry policy configured by source.Retry. Calls are not bounded by the policy, the collector
+// of the source bounds every poll by its current interval, see collector.NewDataServerCollector.
+func NewRetryPolicy(source DataServerClientConfig) client.RetryPolicy {
+	retry := source.Retry
+	policy := client.RetryPolicy{
+		MaxAttempts:            retry.MaxAttempts,
+		BaseBackoff:            time.Millisecond * time.Duration(retry.BaseBackoffMs),
+		MaxBackoff:             time.Millisecond * time.Duration(retry.MaxBackoffMs),
+		RetryableStatusCodes:   retry.RetryableStatusCodes,
+		RetryableNetworkErrors: retry.RetryableNetworkErrors,
+	}
+	if retry.Jitter != nil {
+		policy.Jitter = *retry.Jitter
+	}
+	return policy
+}
+
+// NewCircuitBreaker creates the circuit breaker configured by source.CircuitBreaker, it returns nil when the circuit
+// breaker is disabled.
+func NewCircuitBreaker(source DataServerClientConfig) *client.CircuitBreaker {
+	if source.CircuitBreaker.Disabled {
+		return nil
+	}
+
+	return client.NewCircuitBreaker(source.Name, client.BreakerOptions{
+		FailureThreshold: source.CircuitBreaker.FailureThreshold,
+		CoolDown:         time.Millisecond * time.Duration(source.CircuitBreaker.CoolDownMs),
+	})
+}
+
+// NewDeduplicator creates the deduplicator configured by cfg.Deduplication, it returns nil when deduplication is
+// disabled.
+func NewDeduplicator(cfg Config) (*dedup.Deduplicator, error) {
+	if cfg.Deduplication.Disabled {
+		return nil, nil
+	}
+
+	switch cfg.Deduplication.Action {
+	case dedup.ActionSkip, dedup.ActionStore:
+		return dedup.New(cfg.Deduplication.CacheSize, cfg.Deduplication.Action), nil
+	default:
+		return nil, fmt.Errorf("unknown deduplication action %q", cfg.Deduplication.Action)
+	}
+}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"log/slog"
+	"oc-data-be-challenge/internal/client"
+	"oc-data-be-challenge/internal/dedup"
+	"oc-data-be-challenge/internal/discard"
+	"oc-data-be-challenge/internal/usecase"
 	"os"
 
 	"dario.cat/mergo"
@@ -11,12 +17,24 @@ import (
 type Config struct {
 	// InfluxDBClient holds configuration for InfluxDB client.
 	InfluxDBClient InfluxDBClientConfig `json:"influxdb_client,omitempty"`
-	// DataServerClient holds configuration for the data server client.
-	DataServerClient DataServerClientConfig `json:"data_server_client,omitempty"`
+	// DataServerClient holds configuration for the data servers to collect from, one entry per source.
+	DataServerClient DataSourcesConfig `json:"data_server_client,omitempty"`
 	// HTTPServer holds configuration for the HTTP server.
 	HTTPServer HTTPServerConfig `json:"http_server,omitempty"`
 	// DataServerCollector holds configuration for the data server collector.
 	DataServerCollector DataServerCollectorConfig `json:"data_server_collector,omitempty"`
+	// Storage holds configuration for the data point storage backend.
+	Storage StorageConfig `json:"storage,omitempty"`
+	// DiscardRules are the rules deciding which collected data points are discarded, evaluated in order.
+	DiscardRules []discard.RuleConfig `json:"discard_rules,omitempty"`
+	// WriteAheadLog holds configuration for the write-ahead log between the collector and the storage backend.
+	WriteAheadLog WriteAheadLogConfig `json:"write_ahead_log,omitempty"`
+	// Deduplication holds configuration for the detection of data points collected more than once.
+	Deduplication DeduplicationConfig `json:"deduplication,omitempty"`
+	// Tracing holds configuration for the OpenTelemetry traces.
+	Tracing TracingConfig `json:"tracing,omitempty"`
+	// Health holds configuration for the readiness checks.
+	Health HealthConfig `json:"health,omitempty"`
 }
 
 func (o Config) LogValue() slog.Value {
@@ -25,10 +43,17 @@ func (o Config) LogValue() slog.Value {
 			slog.String("host", o.InfluxDBClient.Host),
 			slog.String("database", o.InfluxDBClient.Database),
 			slog.String("org", o.InfluxDBClient.Org),
+			slog.Any("tag_columns", o.InfluxDBClient.TagColumns),
 		)), // Just show the host
 		slog.Any("data_server_client", o.DataServerClient),
 		slog.Any("http_server", o.HTTPServer),
 		slog.Any("data_server_collector", o.DataServerCollector),
+		slog.Any("storage", o.Storage),
+		slog.Any("discard_rules", o.DiscardRules),
+		slog.Any("write_ahead_log", o.WriteAheadLog),
+		slog.Any("deduplication", o.Deduplication),
+		slog.Any("tracing", o.Tracing),
+		slog.Any("health", o.Health),
 	)
 }
 
@@ -42,6 +67,9 @@ type InfluxDBClientConfig struct {
 	Database string `json:"database,omitempty"`
 	// Org is the InfluxDB organization name.
 	Org string `json:"org,omitempty"`
+	// TagColumns are the tags stored in a tag column of their own so that filters on them are evaluated on indexed
+	// columns, the other tags are only stored in the tag set column.
+	TagColumns []string `json:"tag_columns,omitempty"`
 }
 
 func DefaultInfluxDBClientConfig() InfluxDBClientConfig {
@@ -51,15 +79,128 @@ func DefaultInfluxDBClientConfig() InfluxDBClientConfig {
 	}
 }
 
-// DataServerClientConfig holds configuration for the data server's client.
+// DataSourcesConfig holds configuration for the data servers to collect from.
+type DataSourcesConfig []DataServerClientConfig
+
+// UnmarshalJSON accepts a list of sources, or a single source object as written before sources were introduced.
+func (o *DataSourcesConfig) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var source DataServerClientConfig
+		if err := json.Unmarshal(data, &source); err != nil {
+			return err
+		}
+		if source.Name == "" {
+			source.Name = DefaultDataSourceName
+		}
+		*o = DataSourcesConfig{source}
+		return nil
+	}
+
+	var sources []DataServerClientConfig
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return err
+	}
+	*o = sources
+	return nil
+}
+
+// DefaultDataSourceName is the name of the data source configured by default or by a single source object.
+const DefaultDataSourceName = "default"
+
+// DataServerClientConfig holds configuration for a data source: a data server and how it is collected.
 type DataServerClientConfig struct {
+	// Name identifies the source, it labels the data points collected from it. Required and unique.
+	Name string `json:"name,omitempty"`
 	// Host is the data server host.
 	Host string `json:"host,omitempty"`
+	// PollIntervalMs is the interval in milliseconds at which the source is polled, defaults to
+	// data_server_collector.poll_interval_ms.
+	PollIntervalMs int `json:"poll_interval_ms,omitempty"`
+	// TimeoutMs bounds in milliseconds every request to the data server.
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+	// Batch is true when the data server returns a batch of data points per request, as a JSON array or as newline
+	// delimited JSON objects.
+	Batch bool `json:"batch,omitempty"`
+	// TimeUnit is the unit of the integer timestamps sent by the data server, one of "s", "ms", "us" and "ns", or
+	// "auto" to detect it from their magnitude.
+	TimeUnit string `json:"time_unit,omitempty"`
+	// ValueEncoding holds configuration for decoding the values sent by the data server.
+	ValueEncoding ValueEncodingConfig `json:"value_encoding,omitempty"`
+	// DiscardRules are the rules deciding which data points collected from the source are discarded, defaults to
+	// the top-level discard_rules.
+	DiscardRules []discard.RuleConfig `json:"discard_rules,omitempty"`
+	// Retry holds configuration for retrying failed requests to the data server.
+	Retry RetryConfig `json:"retry,omitempty"`
+	// CircuitBreaker holds configuration for the circuit breaker stopping requests while the data server is down.
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker,omitempty"`
+}
+
+// ValueEncodingConfig holds configuration for decoding the values sent by a data server.
+type ValueEncodingConfig struct {
+	// Type is the encoding of the values, one of "float32", "float64", "int16", "int32" or "number".
+	Type string `json:"type,omitempty"`
+	// ByteOrder is the byte order of binary values, "little" or "big".
+	ByteOrder string `json:"byte_order,omitempty"`
+	// Scale multiplies decoded values.
+	Scale float64 `json:"scale,omitempty"`
+	// Offset is added to decoded values once scaled.
+	Offset float64 `json:"offset,omitempty"`
+}
+
+// CircuitBreakerConfig holds configuration for the circuit breaker around the data server.
+type CircuitBreakerConfig struct {
+	// Disabled keeps polling the data server while it is down.
+	Disabled bool `json:"disabled,omitempty"`
+	// FailureThreshold is the number of consecutive failed polls that opens the circuit breaker.
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+	// CoolDownMs is how long in milliseconds the circuit breaker stays open before probing the data server again.
+	CoolDownMs int `json:"cool_down_ms,omitempty"`
+}
+
+// RetryConfig holds configuration for retrying failed requests to the data server.
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts per poll, including the first one. 1 disables retries.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// BaseBackoffMs is the wait in milliseconds before the first retry, it doubles after every retry.
+	BaseBackoffMs int `json:"base_backoff_ms,omitempty"`
+	// MaxBackoffMs caps in milliseconds the wait between two attempts.
+	MaxBackoffMs int `json:"max_backoff_ms,omitempty"`
+	// Jitter is the fraction, between 0 and 1, of every wait that is randomized.
+	Jitter *float64 `json:"jitter,omitempty"`
+	// RetryableStatusCodes are the response status codes that are retried.
+	RetryableStatusCodes []int `json:"retryable_status_codes,omitempty"`
+	// RetryableNetworkErrors are the kinds of network errors that are retried, any of "timeout", "connection_refused",
+	// "connection_reset" and "dns".
+	RetryableNetworkErrors []string `json:"retryable_network_errors,omitempty"`
 }
 
 func DefaultDataServerConfig() DataServerClientConfig {
+	jitter := 0.2
 	return DataServerClientConfig{
-		Host: "http://localhost:28462",
+		Host:      "http://localhost:28462",
+		TimeoutMs: 10000,
+		TimeUnit:  string(client.TimeUnitAuto),
+		ValueEncoding: ValueEncodingConfig{
+			Type:      string(client.ValueFloat32),
+			ByteOrder: client.ByteOrderLittleEndian,
+			Scale:     1,
+		},
+		Retry: RetryConfig{
+			MaxAttempts:          3,
+			BaseBackoffMs:        100,
+			MaxBackoffMs:         2000,
+			Jitter:               &jitter,
+			RetryableStatusCodes: []int{429, 502, 503, 504},
+			RetryableNetworkErrors: []string{
+				client.NetworkErrorTimeout,
+				client.NetworkErrorConnectionRefused,
+				client.NetworkErrorConnectionReset,
+			},
+		},
+		CircuitBreaker: CircuitBreakerConfig{
+			FailureThreshold: 5,
+			CoolDownMs:       30000,
+		},
 	}
 }
 
@@ -75,9 +216,19 @@ func DefaultHTTPServerConfig() HTTPServerConfig {
 	}
 }
 
+// DefaultDataSource returns the data source collected when none is configured.
+func DefaultDataSource() DataServerClientConfig {
+	source := DefaultDataServerConfig()
+	source.Name = DefaultDataSourceName
+	return source
+}
+
 // DataServerCollectorConfig holds configuration for the data server collector.
 type DataServerCollectorConfig struct {
+	// PollIntervalMs is the default interval in milliseconds at which data sources are polled.
 	PollIntervalMs int `json:"poll_interval_ms,omitempty"`
+	// Instance identifies this collector on the data points it discards, defaults to the host name.
+	Instance string `json:"instance,omitempty"`
 }
 
 func DefaultDataServerCollectorConfig() DataServerCollectorConfig {
@@ -86,13 +237,176 @@ func DefaultDataServerCollectorConfig() DataServerCollectorConfig {
 	}
 }
 
+const (
+	// StorageDriverInfluxDB stores data points in InfluxDB 3, configured by influxdb_client.
+	StorageDriverInfluxDB = "influxdb"
+	// StorageDriverMemory keeps data points in memory, they are lost on restart.
+	StorageDriverMemory = "memory"
+	// StorageDriverEmbedded stores data points on the local disk.
+	StorageDriverEmbedded = "embedded"
+)
+
+// StorageConfig holds configuration for the data point storage backend.
+type StorageConfig struct {
+	// Driver is the storage backend, one of "influxdb", "memory" or "embedded".
+	Driver string `json:"driver,omitempty"`
+	// Embedded holds configuration for the embedded storage driver.
+	Embedded EmbeddedStorageConfig `json:"embedded,omitempty"`
+	// Buffer holds configuration for the write buffer in front of the storage backend.
+	Buffer WriteBufferConfig `json:"buffer,omitempty"`
+}
+
+// WriteBufferConfig holds configuration for the write buffer in front of the storage backend.
+type WriteBufferConfig struct {
+	// Disabled writes every collected data point to the storage backend on its own.
+	Disabled bool `json:"disabled,omitempty"`
+	// MaxBatchSize is the number of buffered data points that triggers a flush.
+	MaxBatchSize int `json:"max_batch_size,omitempty"`
+	// FlushIntervalMs is how often in milliseconds the buffer is flushed.
+	FlushIntervalMs int `json:"flush_interval_ms,omitempty"`
+	// MaxBufferedPoints bounds the buffer, collection blocks once it is full until data points are flushed.
+	MaxBufferedPoints int `json:"max_buffered_points,omitempty"`
+	// WriteTimeoutMs bounds in milliseconds every batch write to the storage backend.
+	WriteTimeoutMs int `json:"write_timeout_ms,omitempty"`
+}
+
+// EmbeddedStorageConfig holds configuration for the embedded storage driver.
+type EmbeddedStorageConfig struct {
+	// Dir is the directory in which data files are stored.
+	Dir string `json:"dir,omitempty"`
+	// PartitionDurationMs is the time span in milliseconds covered by a single segment file.
+	PartitionDurationMs int `json:"partition_duration_ms,omitempty"`
+	// RetentionMs is how long in milliseconds data points are kept, 0 keeps them forever.
+	RetentionMs int `json:"retention_ms,omitempty"`
+}
+
+// WriteBufferIgnored reports whether the write buffer settings were changed from their defaults while the write-ahead
+// log is enabled, its drainer then writes in batches and the write buffer is not used.
+func (o Config) WriteBufferIgnored() bool {
+	return !o.WriteAheadLog.Disabled && !o.Storage.Buffer.Disabled && o.Storage.Buffer != DefaultStorageConfig().Buffer
+}
+
+func DefaultStorageConfig() StorageConfig {
+	return StorageConfig{
+		Driver: StorageDriverInfluxDB,
+		Embedded: EmbeddedStorageConfig{
+			Dir:                 "./data",
+			PartitionDurationMs: 3600000,
+		},
+		Buffer: WriteBufferConfig{
+			MaxBatchSize:      500,
+			FlushIntervalMs:   1000,
+			MaxBufferedPoints: 10000,
+			WriteTimeoutMs:    10000,
+		},
+	}
+}
+
+// WriteAheadLogConfig holds configuration for the write-ahead log between the collector and the storage backend.
+type WriteAheadLogConfig struct {
+	// Disabled writes collected data points to the storage backend directly, they are lost when the write fails.
+	Disabled bool `json:"disabled,omitempty"`
+	// Dir is the directory in which log segments are stored.
+	Dir string `json:"dir,omitempty"`
+	// MaxSegmentSizeBytes is the size in bytes above which a new log segment is started.
+	MaxSegmentSizeBytes int64 `json:"max_segment_size_bytes,omitempty"`
+	// DrainIntervalMs is how often in milliseconds queued data points are written to the storage backend.
+	DrainIntervalMs int `json:"drain_interval_ms,omitempty"`
+}
+
+func DefaultWriteAheadLogConfig() WriteAheadLogConfig {
+	return WriteAheadLogConfig{
+		Dir:                 "./wal",
+		MaxSegmentSizeBytes: 16 << 20,
+		DrainIntervalMs:     1000,
+	}
+}
+
+// DeduplicationConfig holds configuration for the detection of data points collected more than once.
+type DeduplicationConfig struct {
+	// Disabled stores every collected data point, including the ones already collected.
+	Disabled bool `json:"disabled,omitempty"`
+	// CacheSize is the number of recently collected data points duplicates are detected among.
+	CacheSize int `json:"cache_size,omitempty"`
+	// Action is what is done with duplicates, "skip" drops them and "store" writes them to their own table.
+	Action dedup.Action `json:"action,omitempty"`
+}
+
+func DefaultDeduplicationConfig() DeduplicationConfig {
+	return DeduplicationConfig{
+		CacheSize: dedup.DefaultCacheSize,
+		Action:    dedup.ActionSkip,
+	}
+}
+
+const (
+	// TracingExporterNone records no span, the W3C trace context of incoming requests is still propagated.
+	TracingExporterNone = "none"
+	// TracingExporterOTLP sends spans to an OpenTelemetry collector over OTLP/HTTP.
+	TracingExporterOTLP = "otlp"
+	// TracingExporterStdout writes spans to the standard output, for local debugging.
+	TracingExporterStdout = "stdout"
+	// TracingExporterFile writes spans to a file, one JSON object per span.
+	TracingExporterFile = "file"
+)
+
+// TracingConfig holds configuration for the OpenTelemetry traces.
+type TracingConfig struct {
+	// Exporter is where spans are sent, one of "none", "otlp", "stdout" or "file".
+	Exporter string `json:"exporter,omitempty"`
+	// Endpoint is the host and port of the OTLP/HTTP collector, used by "otlp".
+	Endpoint string `json:"endpoint,omitempty"`
+	// Insecure sends spans to the OTLP/HTTP collector over plain HTTP.
+	Insecure bool `json:"insecure,omitempty"`
+	// File is the path of the file spans are appended to, used by "file".
+	File string `json:"file,omitempty"`
+	// ServiceName identifies the service in the traces.
+	ServiceName string `json:"service_name,omitempty"`
+	// SampleRatio is the fraction, between 0 and 1, of the traces started by the service that are recorded. Traces
+	// started upstream follow the sampling decision of their parent.
+	SampleRatio *float64 `json:"sample_ratio,omitempty"`
+}
+
+func DefaultTracingConfig() TracingConfig {
+	sampleRatio := 1.0
+	return TracingConfig{
+		Exporter:    TracingExporterNone,
+		Endpoint:    "localhost:4318",
+		File:        "./traces.json",
+		ServiceName: "oc-data-be-challenge",
+		SampleRatio: &sampleRatio,
+	}
+}
+
+// HealthConfig holds configuration for the readiness checks.
+type HealthConfig struct {
+	// MaxCollectAgePolls is how many poll intervals of a data source may elapse since its last successful collection
+	// before the service is not ready.
+	MaxCollectAgePolls float64 `json:"max_collect_age_polls,omitempty"`
+	// CheckTimeoutMs bounds in milliseconds every check of a component.
+	CheckTimeoutMs int `json:"check_timeout_ms,omitempty"`
+}
+
+func DefaultHealthConfig() HealthConfig {
+	return HealthConfig{
+		MaxCollectAgePolls: 3,
+		CheckTimeoutMs:     2000,
+	}
+}
+
 // DefaultConfig returns the default configuration.
 func DefaultConfig() Config {
 	return Config{
 		InfluxDBClient:      DefaultInfluxDBClientConfig(),
-		DataServerClient:    DefaultDataServerConfig(),
+		DataServerClient:    DataSourcesConfig{DefaultDataSource()},
 		HTTPServer:          DefaultHTTPServerConfig(),
 		DataServerCollector: DefaultDataServerCollectorConfig(),
+		Storage:             DefaultStorageConfig(),
+		DiscardRules:        discard.DefaultRuleConfigs(),
+		WriteAheadLog:       DefaultWriteAheadLogConfig(),
+		Deduplication:       DefaultDeduplicationConfig(),
+		Tracing:             DefaultTracingConfig(),
+		Health:              DefaultHealthConfig(),
 	}
 }
 
@@ -108,8 +422,47 @@ func LoadConfigFromFile(path string) (Config, error) {
 		return Config{}, err
 	}
 
-	if err = mergo.Merge(&cfg, DefaultConfig()); err != nil {
+	// pointers are not dereferenced so that an explicit zero, e.g. "jitter": 0, is kept
+	noDiscardRules := explicitlyEmpty(cfg.DiscardRules)
+	if err = mergo.Merge(&cfg, DefaultConfig(), mergo.WithoutDereference); err != nil {
 		return Config{}, err
 	}
+	if noDiscardRules {
+		cfg.DiscardRules = []discard.RuleConfig{}
+	}
+
+	// every source is merged on its own, the collector poll interval and the top-level discard rules are the
+	// defaults of the sources
+	names := make(map[string]bool, len(cfg.DataServerClient))
+	for i := range cfg.DataServerClient {
+		source := &cfg.DataServerClient[i]
+		if source.Name == "" {
+			return Config{}, fmt.Errorf("data_server_client[%d]: name is required", i)
+		}
+		if names[source.Name] {
+			return Config{}, fmt.Errorf("data_server_client[%d]: duplicate name %q", i, source.Name)
+		}
+		if source.Name == usecase.DefaultPushSource {
+			return Config{}, fmt.Errorf("data_server_client[%d]: name %q is reserved for pushed data points", i, source.Name)
+		}
+		names[source.Name] = true
+
+		defaults := DefaultDataServerConfig()
+		defaults.PollIntervalMs = cfg.DataServerCollector.PollIntervalMs
+		defaults.DiscardRules = cfg.DiscardRules
+		noDiscardRules = explicitlyEmpty(source.DiscardRules)
+		if err = mergo.Merge(source, defaults, mergo.WithoutDereference); err != nil {
+			return Config{}, err
+		}
+		if noDiscardRules {
+			source.DiscardRules = []discard.RuleConfig{}
+		}
+	}
 	return cfg, nil
 }
+
+// explicitlyEmpty reports whether rules were set to an empty list, e.g. "discard_rules": [], to keep every data point.
+// mergo replaces an empty list by the default rules like an unset one.
+func explicitlyEmpty(rules []discard.RuleConfig) bool {
+	return rules != nil && len(rules) == 0
+}
@@ -2,14 +2,25 @@ package main
 
 import (
 	"encoding/json"
+	"oc-data-be-challenge/internal/dedup"
+	"oc-data-be-challenge/internal/discard"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// defaultDataSource returns the data source collected when the configuration has none.
+func defaultDataSource() DataServerClientConfig {
+	source := DefaultDataSource()
+	source.PollIntervalMs = 1000
+	source.DiscardRules = discard.DefaultRuleConfigs()
+	return source
+}
+
 // TestLoadConfigFromFile_ValidJSON tests loading configuration from a valid JSON file.
 func TestLoadConfigFromFile_ValidJSON(t *testing.T) {
 	// Create a temporary file with test configuration
@@ -25,8 +36,8 @@ func TestLoadConfigFromFile_ValidJSON(t *testing.T) {
 			Database: "production",
 			Org:      "myorg",
 		},
-		DataServerClient: DataServerClientConfig{
-			Host: "http://custom-server:9090",
+		DataServerClient: DataSourcesConfig{
+			{Name: "custom", Host: "http://custom-server:9090"},
 		},
 		HTTPServer: HTTPServerConfig{
 			Port: ":9000",
@@ -51,7 +62,9 @@ func TestLoadConfigFromFile_ValidJSON(t *testing.T) {
 	assert.Equal(t, "myorg", cfg.InfluxDBClient.Org)
 
 	// Verify Data Server config
-	assert.Equal(t, "http://custom-server:9090", cfg.DataServerClient.Host)
+	require.Len(t, cfg.DataServerClient, 1)
+	assert.Equal(t, "custom", cfg.DataServerClient[0].Name)
+	assert.Equal(t, "http://custom-server:9090", cfg.DataServerClient[0].Host)
 
 	// Verify HTTP Server config
 	assert.Equal(t, ":9000", cfg.HTTPServer.Port)
@@ -83,8 +96,12 @@ func TestLoadConfigFromFile_PartialJSON(t *testing.T) {
 
 	// Verify default values were merged
 	assert.Equal(t, "dev", cfg.InfluxDBClient.Database)
-	assert.Equal(t, "http://localhost:8080", cfg.DataServerClient.Host)
+	assert.Equal(t, DataSourcesConfig{defaultDataSource()}, cfg.DataServerClient)
 	assert.Equal(t, ":8080", cfg.HTTPServer.Port)
+	assert.Equal(t, StorageDriverInfluxDB, cfg.Storage.Driver)
+	assert.Equal(t, "./data", cfg.Storage.Embedded.Dir)
+	assert.Equal(t, 3600000, cfg.Storage.Embedded.PartitionDurationMs)
+	assert.Equal(t, discard.DefaultRuleConfigs(), cfg.DiscardRules)
 }
 
 // TestLoadConfigFromFile_InvalidFile tests loading configuration from a non-existent file.
@@ -128,6 +145,275 @@ func TestLoadConfigFromFile_EmptyJSON(t *testing.T) {
 	// Verify all defaults are applied
 	assert.Equal(t, "http://influxdb3-core:8181", cfg.InfluxDBClient.Host)
 	assert.Equal(t, "dev", cfg.InfluxDBClient.Database)
-	assert.Equal(t, "http://localhost:8080", cfg.DataServerClient.Host)
+	assert.Equal(t, DataSourcesConfig{defaultDataSource()}, cfg.DataServerClient)
 	assert.Equal(t, ":8080", cfg.HTTPServer.Port)
+	assert.Equal(t, StorageDriverInfluxDB, cfg.Storage.Driver)
+	assert.Equal(t, "./data", cfg.Storage.Embedded.Dir)
+	assert.Equal(t, 3600000, cfg.Storage.Embedded.PartitionDurationMs)
+	assert.Equal(t, DefaultStorageConfig().Buffer, cfg.Storage.Buffer)
+	assert.Equal(t, discard.DefaultRuleConfigs(), cfg.DiscardRules)
+	assert.Equal(t, DefaultWriteAheadLogConfig(), cfg.WriteAheadLog)
+	assert.Equal(t, DefaultDeduplicationConfig(), cfg.Deduplication)
+	assert.Equal(t, DefaultTracingConfig(), cfg.Tracing)
+	assert.Equal(t, DefaultHealthConfig(), cfg.Health)
+}
+
+// TestLoadConfigFromFile_StorageDriver tests selecting a storage driver while keeping the other storage defaults.
+func TestLoadConfigFromFile_StorageDriver(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config-*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	_, err = tmpfile.WriteString(`{"storage": {"driver": "memory"}}`)
+	require.NoError(t, err)
+	tmpfile.Close()
+
+	cfg, err := LoadConfigFromFile(tmpfile.Name())
+	require.NoError(t, err)
+
+	assert.Equal(t, StorageDriverMemory, cfg.Storage.Driver)
+	assert.Equal(t, "./data", cfg.Storage.Embedded.Dir)
+}
+
+// TestLoadConfigFromFile_WriteBuffer tests overriding part of the write buffer configuration.
+func TestLoadConfigFromFile_WriteBuffer(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config-*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	_, err = tmpfile.WriteString(`{"storage": {"buffer": {"disabled": true, "max_batch_size": 50}}}`)
+	require.NoError(t, err)
+	tmpfile.Close()
+
+	cfg, err := LoadConfigFromFile(tmpfile.Name())
+	require.NoError(t, err)
+
+	assert.True(t, cfg.Storage.Buffer.Disabled)
+	assert.Equal(t, 50, cfg.Storage.Buffer.MaxBatchSize)
+	assert.Equal(t, 1000, cfg.Storage.Buffer.FlushIntervalMs)
+	assert.Equal(t, 10000, cfg.Storage.Buffer.MaxBufferedPoints)
+}
+
+// TestConfig_WriteBufferIgnored tests that changed write buffer settings are reported as ignored only while the
+// write-ahead log is enabled.
+func TestConfig_WriteBufferIgnored(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		ignored bool
+	}{
+		{name: "defaults", json: `{}`, ignored: false},
+		{name: "buffer settings", json: `{"storage": {"buffer": {"max_batch_size": 50}}}`, ignored: true},
+		{name: "buffer disabled", json: `{"storage": {"buffer": {"disabled": true, "max_batch_size": 50}}}`, ignored: false},
+		{name: "write-ahead log disabled", json: `{"storage": {"buffer": {"max_batch_size": 50}}, "write_ahead_log": {"disabled": true}}`, ignored: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpfile, err := os.CreateTemp("", "config-*.json")
+			require.NoError(t, err)
+			defer os.Remove(tmpfile.Name())
+
+			_, err = tmpfile.WriteString(tt.json)
+			require.NoError(t, err)
+			tmpfile.Close()
+
+			cfg, err := LoadConfigFromFile(tmpfile.Name())
+			require.NoError(t, err)
+			assert.Equal(t, tt.ignored, cfg.WriteBufferIgnored())
+		})
+	}
+}
+
+// TestLoadConfigFromFile_Deduplication tests overriding the deduplication action while keeping the cache size default.
+func TestLoadConfigFromFile_Deduplication(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config-*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	_, err = tmpfile.WriteString(`{"deduplication": {"action": "store"}}`)
+	require.NoError(t, err)
+	tmpfile.Close()
+
+	cfg, err := LoadConfigFromFile(tmpfile.Name())
+	require.NoError(t, err)
+
+	assert.Equal(t, DeduplicationConfig{CacheSize: 1024, Action: dedup.ActionStore}, cfg.Deduplication)
+	deduplicator, err := NewDeduplicator(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, dedup.ActionStore, deduplicator.Action())
+
+	cfg.Deduplication.Action = "drop"
+	_, err = NewDeduplicator(cfg)
+	assert.Error(t, err)
+}
+
+// TestLoadConfigFromFile_Retry tests overriding part of the retry configuration, including disabling jitter.
+func TestLoadConfigFromFile_Retry(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config-*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	_, err = tmpfile.WriteString(`{"data_server_client": {"retry": {"max_attempts": 5, "jitter": 0}}}`)
+	require.NoError(t, err)
+	tmpfile.Close()
+
+	cfg, err := LoadConfigFromFile(tmpfile.Name())
+	require.NoError(t, err)
+
+	require.Len(t, cfg.DataServerClient, 1)
+	source := cfg.DataServerClient[0]
+	assert.Equal(t, DefaultDataSourceName, source.Name)
+	assert.Equal(t, 5, source.Retry.MaxAttempts)
+	assert.Equal(t, 100, source.Retry.BaseBackoffMs)
+	require.NotNil(t, source.Retry.Jitter)
+	assert.Zero(t, *source.Retry.Jitter)
+
+	policy := NewRetryPolicy(source)
+	assert.Zero(t, policy.MaxElapsed)
+	assert.Equal(t, []int{429, 502, 503, 504}, policy.RetryableStatusCodes)
+}
+
+// TestLoadConfigFromFile_Tracing tests that a sample ratio of 0, recording no trace started by the service, is kept.
+func TestLoadConfigFromFile_Tracing(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config-*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	_, err = tmpfile.WriteString(`{"tracing": {"exporter": "otlp", "sample_ratio": 0}}`)
+	require.NoError(t, err)
+	tmpfile.Close()
+
+	cfg, err := LoadConfigFromFile(tmpfile.Name())
+	require.NoError(t, err)
+
+	assert.Equal(t, TracingExporterOTLP, cfg.Tracing.Exporter)
+	assert.Equal(t, "localhost:4318", cfg.Tracing.Endpoint)
+	require.NotNil(t, cfg.Tracing.SampleRatio)
+	assert.Zero(t, *cfg.Tracing.SampleRatio)
+}
+
+// TestLoadConfigFromFile_DataSources tests that every source is merged with the defaults, inheriting the collector
+// poll interval and the top-level discard rules unless it overrides them.
+func TestLoadConfigFromFile_DataSources(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config-*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	_, err = tmpfile.WriteString(`{
+		"data_server_collector": {"poll_interval_ms": 2000},
+		"data_server_client": [
+			{"name": "north", "host": "http://north:28462"},
+			{
+				"name": "south",
+				"host": "http://south:28462",
+				"poll_interval_ms": 500,
+				"timeout_ms": 250,
+				"batch": true,
+				"discard_rules": [{"name": "finite", "type": "non_finite"}]
+			}
+		]
+	}`)
+	require.NoError(t, err)
+	tmpfile.Close()
+
+	cfg, err := LoadConfigFromFile(tmpfile.Name())
+	require.NoError(t, err)
+	require.Len(t, cfg.DataServerClient, 2)
+
+	north, south := cfg.DataServerClient[0], cfg.DataServerClient[1]
+	assert.Equal(t, "north", north.Name)
+	assert.Equal(t, 2000, north.PollIntervalMs)
+	assert.Equal(t, 10000, north.TimeoutMs)
+	assert.Equal(t, discard.DefaultRuleConfigs(), north.DiscardRules)
+	assert.Equal(t, DefaultDataServerConfig().Retry, north.Retry)
+
+	assert.Equal(t, "south", south.Name)
+	assert.Equal(t, 500, south.PollIntervalMs)
+	assert.Equal(t, 250, south.TimeoutMs)
+	assert.Equal(t, []discard.RuleConfig{{Name: "finite", Type: discard.TypeNonFinite}}, south.DiscardRules)
+
+	sources, err := NewDataSources(cfg)
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+	assert.Equal(t, 500*time.Millisecond, sources[1].PollInterval)
+	assert.False(t, sources[0].Batch)
+	assert.True(t, sources[1].Batch)
+}
+
+// TestLoadConfigFromFile_InvalidDataSources tests that sources must have a unique name, other than the one of pushed
+// data points.
+func TestLoadConfigFromFile_InvalidDataSources(t *testing.T) {
+	for name, sources := range map[string]string{
+		"missing name":   `[{"host": "http://north:28462"}]`,
+		"duplicate name": `[{"name": "north"}, {"name": "north"}]`,
+		"reserved name":  `[{"name": "push"}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			tmpfile, err := os.CreateTemp("", "config-*.json")
+			require.NoError(t, err)
+			defer os.Remove(tmpfile.Name())
+
+			_, err = tmpfile.WriteString(`{"data_server_client": ` + sources + `}`)
+			require.NoError(t, err)
+			tmpfile.Close()
+
+			_, err = LoadConfigFromFile(tmpfile.Name())
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestLoadConfigFromFile_DiscardRules tests that configured discard rules replace the default ones.
+func TestLoadConfigFromFile_DiscardRules(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config-*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	_, err = tmpfile.WriteString(`{"discard_rules": [{"name": "finite", "type": "non_finite"}]}`)
+	require.NoError(t, err)
+	tmpfile.Close()
+
+	cfg, err := LoadConfigFromFile(tmpfile.Name())
+	require.NoError(t, err)
+
+	assert.Equal(t, []discard.RuleConfig{{Name: "finite", Type: discard.TypeNonFinite}}, cfg.DiscardRules)
+}
+
+// TestLoadConfigFromFile_NoDiscardRules tests that discard rules explicitly set to an empty list are not replaced by
+// the default ones, at the top level and per source.
+func TestLoadConfigFromFile_NoDiscardRules(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config-*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	_, err = tmpfile.WriteString(`{
+		"discard_rules": [],
+		"data_server_client": [{"name": "north"}]
+	}`)
+	require.NoError(t, err)
+	tmpfile.Close()
+
+	cfg, err := LoadConfigFromFile(tmpfile.Name())
+	require.NoError(t, err)
+	assert.NotNil(t, cfg.DiscardRules)
+	assert.Empty(t, cfg.DiscardRules)
+	assert.Empty(t, cfg.DataServerClient[0].DiscardRules)
+
+	tmpfile, err = os.CreateTemp("", "config-*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	_, err = tmpfile.WriteString(`{
+		"data_server_client": [{"name": "north", "discard_rules": []}, {"name": "south"}]
+	}`)
+	require.NoError(t, err)
+	tmpfile.Close()
+
+	cfg, err = LoadConfigFromFile(tmpfile.Name())
+	require.NoError(t, err)
+	assert.Equal(t, discard.DefaultRuleConfigs(), cfg.DiscardRules)
+	assert.NotNil(t, cfg.DataServerClient[0].DiscardRules)
+	assert.Empty(t, cfg.DataServerClient[0].DiscardRules)
+	assert.Equal(t, discard.DefaultRuleConfigs(), cfg.DataServerClient[1].DiscardRules)
 }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"oc-data-be-challenge/internal/collector"
+	"oc-data-be-challenge/internal/data/repository"
+	"oc-data-be-challenge/internal/health"
+	"oc-data-be-challenge/internal/usecase"
+	"time"
+)
+
+// NewReadinessChecker creates the checks of the readiness probe: the storage backend is reachable and, for every data
+// source, the data server is reachable and the last successful collection is recent enough. The checks of a data
+// source pass while its collector is paused.
+func NewReadinessChecker(cfg Config, repo repository.DataPointStore, uc *usecase.DataPointUseCase, collectors map[string]*collector.PeriodicTrigger) *health.Checker {
+	checks := []health.Check{{Name: "storage", Fn: repo.Ping}}
+	for _, source := range uc.Sources() {
+		trigger := collectors[source.Name]
+		checks = append(checks,
+			health.Check{Name: "collector:" + source.Name, Fn: unlessPaused(trigger, collectFreshness(uc, source.Name, trigger, cfg.Health.MaxCollectAgePolls))},
+			health.Check{Name: "data_server:" + source.Name, Fn: unlessPaused(trigger, source.Client.Ping)},
+		)
+	}
+	return health.NewChecker(time.Millisecond*time.Duration(cfg.Health.CheckTimeoutMs), checks...)
+}
+
+// unlessPaused returns a check passing without running check while trigger is paused, e.g. during the maintenance of
+// the data server.
+func unlessPaused(trigger *collector.PeriodicTrigger, check func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if trigger.Status().State == collector.TriggerPaused {
+			return nil
+		}
+		return check(ctx)
+	}
+}
+
+// collectFreshness returns a check failing when the data source named source has not been collected successfully
+// within maxAgePolls intervals of its collector.
+func collectFreshness(uc *usecase.DataPointUseCase, source string, trigger *collector.PeriodicTrigger, maxAgePolls float64) func(context.Context) error {
+	return func(context.Context) error {
+		at, ok := uc.LastCollected(source)
+		if !ok {
+			return errors.New("not collected successfully yet")
+		}
+		maxAge := time.Duration(float64(trigger.Status().Interval) * maxAgePolls)
+		if age := time.Since(at); age > maxAge {
+			return fmt.Errorf("last successful collection %s ago, more than %s", age.Round(time.Millisecond), maxAge)
+		}
+		return nil
+	}
+}
@@ -3,11 +3,16 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log/slog"
 	"net/http"
-	"oc-data-be-challenge/internal/client"
 	"oc-data-be-challenge/internal/collector"
 	"oc-data-be-challenge/internal/data/repository"
+	"oc-data-be-challenge/internal/data/wal"
+	"oc-data-be-challenge/internal/discard"
+	"oc-data-be-challenge/internal/health"
+	"oc-data-be-challenge/internal/metrics"
+	"oc-data-be-challenge/internal/tracing"
 	httptransport "oc-data-be-challenge/internal/transport/http"
 	"oc-data-be-challenge/internal/usecase"
 	"oc-data-be-challenge/internal/utils/version"
@@ -17,17 +22,33 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/httplog/v3"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
-var cfgPath string
+var (
+	cfgPath      string
+	migrateTags  bool
+	printVersion bool
+)
 
 func init() {
 	flag.StringVar(&cfgPath, "config file", "./config.json", "Path to configuration file")
+	flag.BoolVar(&migrateTags, "migrate-tags", false, "Rewrite the data points stored with the legacy tags field to tag columns, then exit")
+	flag.BoolVar(&printVersion, "version", false, "Print the build information, then exit")
 }
 
 func main() {
+	// Parse flags
+	flag.Parse()
+
+	// Print the build information and exit
+	if printVersion {
+		fmt.Println(version.BuildInfo{}.Info())
+		return
+	}
+
 	// Setup Logger
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		ReplaceAttr: httplog.SchemaECS.ReplaceAttr,
@@ -35,9 +56,6 @@ func main() {
 	slog.SetDefault(logger)
 	logger.Info("application started", "build_info", version.BuildInfo{}.Info())
 
-	// Parse flags
-	flag.Parse()
-
 	// Load Config
 	cfg, err := LoadConfigFromFile(cfgPath)
 	if err != nil {
@@ -45,41 +63,108 @@ func main() {
 	}
 	logger.Info("application config", "config", cfg)
 
-	// Setup InfluxDB Client
-	influxdb3Client, err := influxdb3.New(influxdb3.ClientConfig{
-		Host:         cfg.InfluxDBClient.Host,
-		Token:        cfg.InfluxDBClient.Token,
-		Database:     cfg.InfluxDBClient.Database,
-		Organization: cfg.InfluxDBClient.Org,
-		WriteOptions: &influxdb3.WriteOptions{
-			NoSync: true,
-		},
-	})
+	// Migrate the stored tags to tag columns and exit
+	if migrateTags {
+		migrated, err := MigrateTags(context.Background(), cfg)
+		if err != nil {
+			panic(err)
+		}
+		logger.Info("Tags migrated", "data_points", migrated)
+		return
+	}
+
+	// Setup Tracing
+	shutdownTracing, err := SetupTracing(context.Background(), cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	// Setup Data Sources, each with its own client and discard rules
+	sources, err := NewDataSources(cfg)
 	if err != nil {
 		panic(err)
 	}
 
-	// Setup Data Server Client
-	dataServerClient := client.NewDataServerClient(cfg.DataServerClient.Host, nil)
+	// Setup Discard Rules of pushed data points not labelled with a data source
+	pushRules, err := discard.NewEngine(cfg.DiscardRules)
+	if err != nil {
+		panic(err)
+	}
+
+	// Setup Deduplicator
+	deduplicator, err := NewDeduplicator(cfg)
+	if err != nil {
+		panic(err)
+	}
 
 	// Setup Repository
-	repo := repository.NewDataPoint(influxdb3Client)
+	repo, repoCloser, err := NewDataPointStore(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	// Setup Write-Ahead Log
+	var queue *wal.Log
+	if !cfg.WriteAheadLog.Disabled {
+		queue, err = NewWriteAheadLog(cfg)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// Setup Write Buffer, the write-ahead log drainer already writes in batches and must only commit written data points
+	var writeBuffer *repository.BufferedDataPoint
+	if !cfg.Storage.Buffer.Disabled && queue == nil {
+		writeBuffer = NewWriteBuffer(cfg, repo)
+		repo = writeBuffer
+	}
+	if cfg.WriteBufferIgnored() {
+		logger.Warn("Write buffer settings are ignored while the write-ahead log is enabled, disable the write-ahead log to use the write buffer")
+	}
 
 	// Setup UseCase
-	uc := usecase.NewDataPointUseCase(repo, dataServerClient)
+	collectorInstance := cfg.DataServerCollector.Instance
+	if collectorInstance == "" {
+		collectorInstance, _ = os.Hostname()
+	}
+	uc := usecase.NewDataPointUseCase(repo, sources, pushRules, queue, deduplicator, collectorInstance)
+
+	// Setup and Start Write-Ahead Log Drainer
+	var walDrainer *collector.PeriodicTrigger
+	walDrainerWg := sync.WaitGroup{}
+	if queue != nil {
+		walDrainer = collector.NewWriteAheadLogDrainer(uc, time.Millisecond*time.Duration(cfg.WriteAheadLog.DrainIntervalMs))
+		walDrainerWg.Add(1)
+		go func() {
+			defer walDrainerWg.Done()
+			walDrainer.Start()
+		}()
+	}
 
-	// Setup and Start Data Collector
-	dataCollector := collector.NewDataServerCollector(uc, time.Millisecond*time.Duration(cfg.DataServerCollector.PollIntervalMs))
+	// Setup and Start one Data Collector per source
+	dataCollectors := make(map[string]*collector.PeriodicTrigger, len(sources))
 	dataCollectorWg := sync.WaitGroup{}
-	go func() {
+	for _, source := range sources {
+		dataCollector := collector.NewDataServerCollector(uc, source, source.PollInterval)
+		dataCollectors[source.Name] = dataCollector
 		dataCollectorWg.Add(1)
-		defer dataCollectorWg.Done()
-		dataCollector.Start()
-	}()
+		go func() {
+			defer dataCollectorWg.Done()
+			dataCollector.Start()
+		}()
+	}
 
-	// Setup and Start HTTP server
-	handler := httptransport.HandlerWithOptions(httptransport.NewChiServer(uc), httptransport.ChiServerOptions{
+	// Setup and Start HTTP server, serving the API, the Prometheus metrics and the liveness and readiness probes
+	router := chi.NewRouter()
+	router.Use(httptransport.VersionHeader)
+	router.Handle("/metrics", metrics.Handler())
+	router.Handle("/healthz", health.LivenessHandler())
+	router.Handle("/readyz", NewReadinessChecker(cfg, repo, uc, dataCollectors).Handler())
+	handler := httptransport.HandlerWithOptions(httptransport.NewChiServer(uc, dataCollectors), httptransport.ChiServerOptions{
+		BaseRouter: router,
 		Middlewares: []httptransport.MiddlewareFunc{
+			metrics.HTTPMiddleware,
+			tracing.RouteMiddleware,
 			httplog.RequestLogger(logger.With("component", "HTTPServer"), &httplog.Options{
 				Level:         slog.LevelInfo,
 				Schema:        httplog.SchemaECS,
@@ -88,9 +173,10 @@ func main() {
 		},
 	})
 
+	// every request gets a span, child of the W3C trace context of the request when there is one
 	server := &http.Server{
 		Addr:    cfg.HTTPServer.Port,
-		Handler: handler,
+		Handler: otelhttp.NewHandler(handler, "http.server", otelhttp.WithPropagators(tracing.Propagator)),
 	}
 
 	// Start HTTP server in a goroutine
@@ -112,9 +198,11 @@ func main() {
 	case sig := <-shutdown:
 		logger.Info("Shutdown signal received", "signal", sig)
 
-		// Stop the data collector
-		logger.Info("Stopping data collector")
-		dataCollector.Stop()
+		// Stop the data collectors
+		logger.Info("Stopping data collectors", "sources", len(dataCollectors))
+		for _, dataCollector := range dataCollectors {
+			dataCollector.Stop()
+		}
 		dataCollectorWg.Wait()
 
 		// Create a context with timeout for shutdown
@@ -128,10 +216,38 @@ func main() {
 			_ = server.Close()
 		}
 
-		// Close InfluxDB client
-		logger.Info("Closing InfluxDB client")
-		if err := influxdb3Client.Close(); err != nil {
-			logger.Error("InfluxDB client close error", "error", err)
+		// Drain the write-ahead log before closing the storage backend, data points left in it are written after restart
+		if walDrainer != nil {
+			logger.Info("Stopping write-ahead log drainer")
+			walDrainer.Stop()
+			walDrainerWg.Wait()
+			if err := uc.Drain(ctx); err != nil {
+				logger.Error("Write-ahead log drain error", "error", err)
+			}
+			if err := queue.Close(); err != nil {
+				logger.Error("Write-ahead log close error", "error", err)
+			}
+		}
+
+		// Flush buffered data points before closing the storage backend
+		if writeBuffer != nil {
+			logger.Info("Flushing write buffer", "buffered", writeBuffer.Len())
+			if err := writeBuffer.Close(ctx); err != nil {
+				logger.Error("Write buffer flush error", "error", err)
+			}
+		}
+
+		// Close storage backend
+		if repoCloser != nil {
+			logger.Info("Closing storage backend", "driver", cfg.Storage.Driver)
+			if err := repoCloser.Close(); err != nil {
+				logger.Error("Storage backend close error", "error", err)
+			}
+		}
+
+		// Flush the spans not yet exported
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("Tracing shutdown error", "error", err)
 		}
 
 		logger.Info("Application shutdown complete")
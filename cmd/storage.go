@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"oc-data-be-challenge/internal/data/repository"
+	"oc-data-be-challenge/internal/data/segment"
+	"oc-data-be-challenge/internal/data/wal"
+	"time"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+)
+
+// NewDataPointStore creates the storage backend selected by cfg.Storage.Driver.
+// The returned io.Closer releases the resources held by the backend, it is nil when there is nothing to release.
+func NewDataPointStore(cfg Config) (repository.DataPointStore, io.Closer, error) {
+	switch cfg.Storage.Driver {
+	case StorageDriverInfluxDB:
+		influxdb3Client, err := influxdb3.New(influxdb3.ClientConfig{
+			Host:         cfg.InfluxDBClient.Host,
+			Token:        cfg.InfluxDBClient.Token,
+			Database:     cfg.InfluxDBClient.Database,
+			Organization: cfg.InfluxDBClient.Org,
+			WriteOptions: &influxdb3.WriteOptions{
+				NoSync: true,
+			},
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create InfluxDB client: %w", err)
+		}
+		return repository.NewDataPoint(influxdb3Client, cfg.InfluxDBClient.TagColumns), influxdb3Client, nil
+	case StorageDriverMemory:
+		return repository.NewMemoryDataPoint(), nil, nil
+	case StorageDriverEmbedded:
+		store, err := repository.NewEmbeddedDataPoint(cfg.Storage.Embedded.Dir, segment.Options{
+			PartitionDuration: time.Millisecond * time.Duration(cfg.Storage.Embedded.PartitionDurationMs),
+			Retention:         time.Millisecond * time.Duration(cfg.Storage.Embedded.RetentionMs),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open embedded storage: %w", err)
+		}
+		return store, store, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
+
+// MigrateTags rewrites the data points stored with the legacy tags field to tag columns, see
+// repository.DataPoint.MigrateTags. Only the InfluxDB storage backend stores tags in columns.
+func MigrateTags(ctx context.Context, cfg Config) (int, error) {
+	if cfg.Storage.Driver != StorageDriverInfluxDB {
+		return 0, fmt.Errorf("storage driver %q has nothing to migrate", cfg.Storage.Driver)
+	}
+
+	store, closer, err := NewDataPointStore(cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer closer.Close()
+
+	migrated, err := store.(*repository.DataPoint).MigrateTags(ctx)
+	if err != nil {
+		return migrated, fmt.Errorf("failed to migrate tags: %w", err)
+	}
+	return migrated, nil
+}
+
+// NewWriteBuffer wraps store with the write buffer configured by cfg.Storage.Buffer.
+func NewWriteBuffer(cfg Config, store repository.DataPointStore) *repository.BufferedDataPoint {
+	return repository.NewBufferedDataPoint(store, repository.BufferOptions{
+		MaxBatchSize:      cfg.Storage.Buffer.MaxBatchSize,
+		FlushInterval:     time.Millisecond * time.Duration(cfg.Storage.Buffer.FlushIntervalMs),
+		MaxBufferedPoints: cfg.Storage.Buffer.MaxBufferedPoints,
+		WriteTimeout:      time.Millisecond * time.Duration(cfg.Storage.Buffer.WriteTimeoutMs),
+	})
+}
+
+// NewWriteAheadLog opens the write-ahead log configured by cfg.WriteAheadLog.
+func NewWriteAheadLog(cfg Config) (*wal.Log, error) {
+	log, err := wal.Open(cfg.WriteAheadLog.Dir, wal.Options{
+		MaxSegmentSize: cfg.WriteAheadLog.MaxSegmentSizeBytes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	return log, nil
+}
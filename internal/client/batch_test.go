@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestDataServerClient_DataPoints tests decoding batches sent as a JSON array, as NDJSON or as a single object, where
+// invalid elements are reported without ending the batch.
+func TestDataServerClient_DataPoints(t *testing.T) {
+	value := toJSONByteArray(float32ToBytes(1.5))
+	tests := []struct {
+		name        string
+		body        string
+		expectValid int
+		expectErrs  int
+	}{
+		{
+			name:        "array",
+			body:        `[{"time":1700000000,"value":` + value + `,"tags":[]},{"time":1700000001,"value":` + value + `,"tags":["a"]}]`,
+			expectValid: 2,
+		},
+		{
+			name:        "NDJSON",
+			body:        `{"time":1700000000,"value":` + value + `,"tags":[]}` + "\n" + `{"time":1700000001,"value":` + value + `,"tags":[]}` + "\n",
+			expectValid: 2,
+		},
+		{
+			name:        "single object",
+			body:        `  {"time":1700000000,"value":` + value + `,"tags":[]}`,
+			expectValid: 1,
+		},
+		{
+			name:        "invalid elements",
+			body:        `[{"value":` + value + `,"tags":[]},{"time":1700000000,"value":[1,2,3],"tags":[]},{"time":1700000000,"value":` + value + `,"tags":[]}]`,
+			expectValid: 1,
+			expectErrs:  2,
+		},
+		{
+			name:        "empty array",
+			body:        `[]`,
+			expectValid: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			client := NewDataServerClient("test", server.URL, nil, RetryPolicy{}, nil, DecodeOptions{})
+
+			valid, errs := 0, 0
+			for dp, err := range client.DataPoints(context.Background()) {
+				if err != nil {
+					if !errors.Is(err, ErrInvalidDataPoint) {
+						t.Fatalf("expected ErrInvalidDataPoint, got %v", err)
+					}
+					errs++
+					continue
+				}
+				if dp.Value.Value != 1.5 {
+					t.Errorf("expected Value 1.5, got %f", dp.Value.Value)
+				}
+				valid++
+			}
+
+			if valid != tt.expectValid {
+				t.Errorf("expected %d valid data points, got %d", tt.expectValid, valid)
+			}
+			if errs != tt.expectErrs {
+				t.Errorf("expected %d invalid data points, got %d", tt.expectErrs, errs)
+			}
+		})
+	}
+}
+
+// TestDataServerClient_DataPointsMalformed tests that a malformed batch ends the iteration with an error.
+func TestDataServerClient_DataPointsMalformed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"time":1700000000,"value":` + toJSONByteArray(float32ToBytes(1.5)) + `,"tags":[]},{broken`))
+	}))
+	defer server.Close()
+	client := NewDataServerClient("test", server.URL, nil, RetryPolicy{}, nil, DecodeOptions{})
+
+	var results []error
+	for _, err := range client.DataPoints(context.Background()) {
+		results = append(results, err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0] != nil {
+		t.Errorf("unexpected error: %v", results[0])
+	}
+	if !errors.Is(results[1], ErrMalformedBody) {
+		t.Errorf("expected ErrMalformedBody, got %v", results[1])
+	}
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the data server while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState string
+
+const (
+	// BreakerClosed lets every request through.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen rejects every request until the cool-down has elapsed.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe request through, its outcome closes or reopens the breaker.
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// BreakerOptions configures a CircuitBreaker.
+type BreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures that opens the breaker. Defaults to
+	// DefaultBreakerFailureThreshold.
+	FailureThreshold int
+	// CoolDown is how long the breaker stays open before a probe request is let through. Defaults to
+	// DefaultBreakerCoolDown.
+	CoolDown time.Duration
+}
+
+const (
+	DefaultBreakerFailureThreshold = 5
+	DefaultBreakerCoolDown         = 30 * time.Second
+)
+
+// BreakerStatus is a snapshot of a CircuitBreaker.
+type BreakerStatus struct {
+	State BreakerState
+	// ConsecutiveFailures is the number of failures since the last success.
+	ConsecutiveFailures int
+	// ChangedAt is the time of the last state change, zero if the breaker never left the closed state.
+	ChangedAt time.Time
+	// RetryAt is the time after which a probe request is let through, only set while the breaker is open.
+	RetryAt time.Time
+}
+
+// CircuitBreaker stops calling the data server after FailureThreshold consecutive failures. Once CoolDown has
+// elapsed a single probe request is let through: its success closes the breaker, its failure opens it again for
+// another CoolDown. Every state change is logged once.
+type CircuitBreaker struct {
+	opts   BreakerOptions
+	now    func() time.Time
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	changedAt time.Time
+	probing   bool
+}
+
+// NewCircuitBreaker creates a closed CircuitBreaker for the data source name, which labels its logs.
+func NewCircuitBreaker(name string, opts BreakerOptions) *CircuitBreaker {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = DefaultBreakerFailureThreshold
+	}
+	if opts.CoolDown <= 0 {
+		opts.CoolDown = DefaultBreakerCoolDown
+	}
+
+	return &CircuitBreaker{
+		opts:   opts,
+		now:    time.Now,
+		logger: slog.With("component", "CircuitBreaker", "source", name),
+		state:  BreakerClosed,
+	}
+}
+
+// Allow reports whether a request may be sent, it returns ErrCircuitOpen while the breaker is open or a probe is
+// already in flight. Every allowed request must be followed by a call to Success or Failure.
+func (cb *CircuitBreaker) Allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case BreakerOpen:
+		if cb.now().Before(cb.changedAt.Add(cb.opts.CoolDown)) {
+			return ErrCircuitOpen
+		}
+		cb.setState(BreakerHalfOpen)
+		cb.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if cb.probing {
+			return ErrCircuitOpen
+		}
+		cb.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Success records a successful request, it closes the breaker.
+func (cb *CircuitBreaker) Success() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures = 0
+	cb.probing = false
+	if cb.state != BreakerClosed {
+		cb.setState(BreakerClosed)
+	}
+}
+
+// Failure records a failed request, it opens the breaker when the probe failed or the failure threshold is reached.
+func (cb *CircuitBreaker) Failure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	cb.probing = false
+	switch {
+	case cb.state == BreakerHalfOpen:
+		cb.setState(BreakerOpen)
+	case cb.state == BreakerClosed && cb.failures >= cb.opts.FailureThreshold:
+		cb.setState(BreakerOpen)
+	}
+}
+
+// abandon records an allowed request that was cancelled by the caller, it leaves the state unchanged.
+func (cb *CircuitBreaker) abandon() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.probing = false
+}
+
+// Status returns a snapshot of the breaker.
+func (cb *CircuitBreaker) Status() BreakerStatus {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	status := BreakerStatus{State: cb.state, ConsecutiveFailures: cb.failures, ChangedAt: cb.changedAt}
+	if cb.state == BreakerOpen {
+		status.RetryAt = cb.changedAt.Add(cb.opts.CoolDown)
+	}
+	return status
+}
+
+// setState moves the breaker to state and logs the change, cb.mu must be held.
+func (cb *CircuitBreaker) setState(state BreakerState) {
+	from := cb.state
+	cb.state = state
+	cb.changedAt = cb.now()
+
+	switch state {
+	case BreakerOpen:
+		cb.logger.Warn("Circuit breaker opened", "from", from, "failures", cb.failures, "cool_down", cb.opts.CoolDown)
+	case BreakerHalfOpen:
+		cb.logger.Info("Circuit breaker half-open, probing data server", "from", from)
+	case BreakerClosed:
+		cb.logger.Info("Circuit breaker closed", "from", from)
+	}
+}
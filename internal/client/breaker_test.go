@@ -0,0 +1,180 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestBreaker returns a breaker whose clock is advanced by the returned function.
+func newTestBreaker(opts BreakerOptions) (*CircuitBreaker, func(time.Duration)) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cb := NewCircuitBreaker("test", opts)
+	cb.now = func() time.Time { return now }
+	return cb, func(d time.Duration) { now = now.Add(d) }
+}
+
+// TestCircuitBreaker_States tests the transitions between the closed, open and half-open states.
+func TestCircuitBreaker_States(t *testing.T) {
+	cb, advance := newTestBreaker(BreakerOptions{FailureThreshold: 2, CoolDown: time.Minute})
+
+	for range 2 {
+		if err := cb.Allow(); err != nil {
+			t.Fatalf("expected closed breaker to allow requests, got %v", err)
+		}
+		cb.Failure()
+	}
+	if state := cb.Status().State; state != BreakerOpen {
+		t.Fatalf("expected breaker open after 2 failures, got %s", state)
+	}
+	if err := cb.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen during cool-down, got %v", err)
+	}
+	if retryAt := cb.Status().RetryAt; !retryAt.Equal(cb.Status().ChangedAt.Add(time.Minute)) {
+		t.Errorf("expected retry one cool-down after opening, got %s", retryAt)
+	}
+
+	// a failed probe opens the breaker again
+	advance(time.Minute)
+	if err := cb.Allow(); err != nil {
+		t.Fatalf("expected probe after cool-down, got %v", err)
+	}
+	if err := cb.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected a single probe in flight, got %v", err)
+	}
+	cb.Failure()
+	if state := cb.Status().State; state != BreakerOpen {
+		t.Fatalf("expected breaker open after failed probe, got %s", state)
+	}
+
+	// a successful probe closes the breaker
+	advance(time.Minute)
+	if err := cb.Allow(); err != nil {
+		t.Fatalf("expected probe after cool-down, got %v", err)
+	}
+	if state := cb.Status().State; state != BreakerHalfOpen {
+		t.Fatalf("expected breaker half-open while probing, got %s", state)
+	}
+	cb.Success()
+	status := cb.Status()
+	if status.State != BreakerClosed || status.ConsecutiveFailures != 0 || !status.RetryAt.IsZero() {
+		t.Errorf("expected closed breaker without failures, got %+v", status)
+	}
+}
+
+// TestCircuitBreaker_LogsSource tests that the state changes are logged with the data source of the breaker.
+func TestCircuitBreaker_LogsSource(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	cb := NewCircuitBreaker("north", BreakerOptions{FailureThreshold: 1})
+	cb.Failure()
+
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a single log entry, got %q: %v", logs.String(), err)
+	}
+	if entry["msg"] != "Circuit breaker opened" || entry["source"] != "north" {
+		t.Errorf("expected the opening of the breaker of north to be logged, got %v", entry)
+	}
+}
+
+// TestDataServerClient_CircuitBreaker tests that the data server is not contacted while the breaker is open.
+func TestDataServerClient_CircuitBreaker(t *testing.T) {
+	server, calls := newFlakyServer(t, 3, http.StatusInternalServerError)
+	cb, advance := newTestBreaker(BreakerOptions{FailureThreshold: 3, CoolDown: time.Minute})
+	client := NewDataServerClient("test", server.URL, nil, RetryPolicy{}, cb, DecodeOptions{})
+
+	for range 5 {
+		if _, err := client.DataPoint(context.Background()); err == nil {
+			t.Fatalf("expected error, got nil")
+		}
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected 3 requests before the breaker opened, got %d", got)
+	}
+	if _, err := client.DataPoint(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	advance(time.Minute)
+	if _, err := client.DataPoint(context.Background()); err != nil {
+		t.Fatalf("expected probe to succeed, got %v", err)
+	}
+	if state := cb.Status().State; state != BreakerClosed {
+		t.Errorf("expected breaker closed after successful probe, got %s", state)
+	}
+}
+
+// TestDataServerClient_CircuitBreakerHangingServer tests that requests to a data server that never answers count as
+// failures once the deadline of the caller is reached, while requests cancelled by the caller do not.
+func TestDataServerClient_CircuitBreakerHangingServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	cb, _ := newTestBreaker(BreakerOptions{FailureThreshold: 3, CoolDown: time.Minute})
+	client := NewDataServerClient("test", server.URL, nil, RetryPolicy{
+		MaxAttempts:            3,
+		BaseBackoff:            time.Millisecond,
+		RetryableNetworkErrors: []string{NetworkErrorTimeout},
+	}, cb, DecodeOptions{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, err := client.DataPoint(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if status := cb.Status(); status.State != BreakerClosed || status.ConsecutiveFailures != 0 {
+		t.Fatalf("expected a cancelled request not to count as a failure, got %+v", status)
+	}
+
+	for range 3 {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := client.DataPoint(ctx)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+		if kind := networkErrorKind(err); kind != NetworkErrorTimeout {
+			t.Errorf("expected %q error, got %q: %v", NetworkErrorTimeout, kind, err)
+		}
+	}
+	if status := cb.Status(); status.State != BreakerOpen || status.ConsecutiveFailures != 3 {
+		t.Errorf("expected breaker open after 3 timed out requests, got %+v", status)
+	}
+}
+
+// TestDataServerClient_Ping tests that the data server is reachable without requesting a data point, and that it is
+// reported down while the breaker is open or once it stops listening.
+func TestDataServerClient_Ping(t *testing.T) {
+	server, calls := newFlakyServer(t, 0, http.StatusOK)
+	cb, _ := newTestBreaker(BreakerOptions{FailureThreshold: 1, CoolDown: time.Minute})
+	client := NewDataServerClient("test", server.URL, nil, RetryPolicy{}, cb, DecodeOptions{})
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("expected data server reachable, got %v", err)
+	}
+	if got := calls.Load(); got != 0 {
+		t.Errorf("expected no request, got %d", got)
+	}
+
+	cb.Failure()
+	if err := client.Ping(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	server.Close()
+	client = NewDataServerClient("test", server.URL, nil, RetryPolicy{}, nil, DecodeOptions{})
+	if err := client.Ping(context.Background()); err == nil {
+		t.Errorf("expected error once the data server is closed, got nil")
+	}
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// ValueEncoding is the encoding of the values sent by a data server.
+type ValueEncoding string
+
+const (
+	// ValueFloat32 is an IEEE 754 single precision float sent as 4 base64 encoded bytes.
+	ValueFloat32 ValueEncoding = "float32"
+	// ValueFloat64 is an IEEE 754 double precision float sent as 8 base64 encoded bytes.
+	ValueFloat64 ValueEncoding = "float64"
+	// ValueInt16 is a two's complement integer sent as 2 base64 encoded bytes.
+	ValueInt16 ValueEncoding = "int16"
+	// ValueInt32 is a two's complement integer sent as 4 base64 encoded bytes.
+	ValueInt32 ValueEncoding = "int32"
+	// ValueNumber is a plain JSON number.
+	ValueNumber ValueEncoding = "number"
+)
+
+// Byte orders accepted by NewValueCodec.
+const (
+	ByteOrderLittleEndian = "little"
+	ByteOrderBigEndian    = "big"
+)
+
+// ValueCodec decodes the raw JSON value of a data point.
+type ValueCodec interface {
+	Decode(data []byte) (float64, error)
+}
+
+// DefaultValueCodec decodes the 4 bytes little-endian float32 values sent by the original data server.
+var DefaultValueCodec ValueCodec = BinaryCodec{Encoding: ValueFloat32, Order: binary.LittleEndian, Scale: 1}
+
+// NewValueCodec returns the codec of encoding. byteOrder is "little" or "big", an empty byte order is little-endian,
+// it is ignored for ValueNumber. Decoded values are multiplied by scale, 1 when it is 0, then added offset.
+func NewValueCodec(encoding ValueEncoding, byteOrder string, scale float64, offset float64) (ValueCodec, error) {
+	if scale == 0 {
+		scale = 1
+	}
+
+	if encoding == ValueNumber {
+		return NumberCodec{Scale: scale, Offset: offset}, nil
+	}
+
+	var order binary.ByteOrder
+	switch byteOrder {
+	case "", ByteOrderLittleEndian:
+		order = binary.LittleEndian
+	case ByteOrderBigEndian:
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("unknown byte order %q, expected little or big", byteOrder)
+	}
+
+	codec := BinaryCodec{Encoding: encoding, Order: order, Scale: scale, Offset: offset}
+	if codec.size() == 0 {
+		return nil, fmt.Errorf("unknown value encoding %q, expected one of float32, float64, int16, int32 or number", encoding)
+	}
+	return codec, nil
+}
+
+// BinaryCodec decodes values sent as base64 encoded bytes.
+type BinaryCodec struct {
+	// Encoding is one of ValueFloat32, ValueFloat64, ValueInt16 or ValueInt32.
+	Encoding ValueEncoding
+	Order    binary.ByteOrder
+	// Scale and Offset convert the decoded value, e.g. a raw integer to a physical value: value*Scale + Offset.
+	Scale  float64
+	Offset float64
+}
+
+// size returns the number of bytes of a value, 0 for an unknown encoding.
+func (bc BinaryCodec) size() int {
+	switch bc.Encoding {
+	case ValueInt16:
+		return 2
+	case ValueFloat32, ValueInt32:
+		return 4
+	case ValueFloat64:
+		return 8
+	default:
+		return 0
+	}
+}
+
+func (bc BinaryCodec) Decode(data []byte) (float64, error) {
+	var b []byte
+	if err := json.Unmarshal(data, &b); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal DataPointValue, raw: %s, err:%w", string(data), err)
+	}
+
+	if size := bc.size(); len(b) != size {
+		return 0, fmt.Errorf("invalid data length for DataPointValue, expected %d bytes, got %d bytes", size, len(b))
+	}
+
+	var v float64
+	switch bc.Encoding {
+	case ValueInt16:
+		v = float64(int16(bc.Order.Uint16(b)))
+	case ValueInt32:
+		v = float64(int32(bc.Order.Uint32(b)))
+	case ValueFloat64:
+		v = math.Float64frombits(bc.Order.Uint64(b))
+	default:
+		v = float64(math.Float32frombits(bc.Order.Uint32(b)))
+	}
+	return v*bc.Scale + bc.Offset, nil
+}
+
+// NumberCodec decodes values sent as plain JSON numbers.
+type NumberCodec struct {
+	// Scale and Offset convert the decoded value: value*Scale + Offset.
+	Scale  float64
+	Offset float64
+}
+
+func (nc NumberCodec) Decode(data []byte) (float64, error) {
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal DataPointValue, raw: %s, err:%w", string(data), err)
+	}
+	return v*nc.Scale + nc.Offset, nil
+}
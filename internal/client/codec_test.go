@@ -0,0 +1,122 @@
+package client
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestValueCodecs tests decoding values of every encoding and byte order.
+func TestValueCodecs(t *testing.T) {
+	tests := []struct {
+		name      string
+		encoding  ValueEncoding
+		byteOrder string
+		scale     float64
+		offset    float64
+		input     string
+		expectVal float64
+		expectErr bool
+	}{
+		{
+			name:      "float32 little-endian",
+			encoding:  ValueFloat32,
+			input:     toJSONByteArray(binary.LittleEndian.AppendUint32(nil, math.Float32bits(1.5))),
+			expectVal: 1.5,
+		},
+		{
+			name:      "float32 big-endian",
+			encoding:  ValueFloat32,
+			byteOrder: ByteOrderBigEndian,
+			input:     toJSONByteArray(binary.BigEndian.AppendUint32(nil, math.Float32bits(-2.25))),
+			expectVal: -2.25,
+		},
+		{
+			name:      "float64 keeps precision",
+			encoding:  ValueFloat64,
+			input:     toJSONByteArray(binary.LittleEndian.AppendUint64(nil, math.Float64bits(0.1))),
+			expectVal: 0.1,
+		},
+		{
+			// -125 * 0.1 - 40
+			name:      "int16 with scale and offset",
+			encoding:  ValueInt16,
+			byteOrder: ByteOrderBigEndian,
+			scale:     0.1,
+			offset:    -40,
+			input:     toJSONByteArray(binary.BigEndian.AppendUint16(nil, 0xff83)),
+			expectVal: -52.5,
+		},
+		{
+			name:      "int32",
+			encoding:  ValueInt32,
+			input:     toJSONByteArray(binary.LittleEndian.AppendUint32(nil, 0xfffeee90)),
+			expectVal: -70000,
+		},
+		{
+			name:      "number",
+			encoding:  ValueNumber,
+			input:     `21.125`,
+			expectVal: 21.125,
+		},
+		{
+			name:      "float64 with 4 bytes",
+			encoding:  ValueFloat64,
+			input:     toJSONByteArray(float32ToBytes(1.5)),
+			expectErr: true,
+		},
+		{
+			name:      "number as bytes",
+			encoding:  ValueNumber,
+			input:     toJSONByteArray(float32ToBytes(1.5)),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec, err := NewValueCodec(tt.encoding, tt.byteOrder, tt.scale, tt.offset)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			v, err := codec.Decode([]byte(tt.input))
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(v-tt.expectVal) > 1e-9 {
+				t.Errorf("expected Value %v, got %v", tt.expectVal, v)
+			}
+		})
+	}
+}
+
+// TestNewValueCodec tests that unknown encodings and byte orders are rejected.
+func TestNewValueCodec(t *testing.T) {
+	if _, err := NewValueCodec("float16", "", 1, 0); err == nil {
+		t.Errorf("expected error for unknown encoding, got nil")
+	}
+	if _, err := NewValueCodec(ValueFloat32, "middle", 1, 0); err == nil {
+		t.Errorf("expected error for unknown byte order, got nil")
+	}
+}
+
+// TestDecodeDatapointBody_ValueCodec tests that the value codec of the client is used.
+func TestDecodeDatapointBody_ValueCodec(t *testing.T) {
+	client := &DataServerClient{decode: DecodeOptions{ValueCodec: NumberCodec{Scale: 1}}}
+
+	dp, err := client.decodeDatapointBody(strings.NewReader(`{"time":1700000000,"value":0.1,"tags":[]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dp.Value.Value != 0.1 {
+		t.Errorf("expected Value 0.1, got %v", dp.Value.Value)
+	}
+}
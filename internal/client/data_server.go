@@ -1,18 +1,32 @@
 package client
 
 import (
+	"bufio"
 	"context"
-	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"math"
+	"iter"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"oc-data-be-challenge/internal/metrics"
+	"oc-data-be-challenge/internal/tracing"
 	"strconv"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrInvalidDataPoint is returned for data points failing to decode or to pass DataPoint.IsValid.
+var ErrInvalidDataPoint = errors.New("invalid datapoint received")
+
+// ErrMalformedBody is returned when a batch of data points cannot be decoded to its end.
+var ErrMalformedBody = errors.New("malformed body")
+
 // DataPoint represents a data point received from the data server.
 type DataPoint struct {
 	Time  DataPointTime   `json:"time"`
@@ -26,7 +40,7 @@ func (dp DataPoint) IsValid() (bool, error) {
 	}
 
 	if !dp.Value.Processed {
-		return false, errors.New("value field is not Processed")
+		return false, errors.New("Value field is not Processed")
 	}
 
 	if !dp.Tags.Processed {
@@ -55,36 +69,41 @@ func (t *Value[T]) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-// DataPointTime represents the Time of a data point.
+// DataPointTime represents the Time of a data point, an integer unix timestamp or an RFC3339 string.
 type DataPointTime Value[time.Time]
 
+// UnmarshalJSON parses the time detecting the unit of integer timestamps from their magnitude.
 func (t *DataPointTime) UnmarshalJSON(data []byte) error {
-	ts, err := strconv.ParseInt(string(data), 10, 64)
+	return t.unmarshal(data, TimeUnitAuto)
+}
+
+func (t *DataPointTime) unmarshal(data []byte, unit TimeUnit) error {
+	ts, err := parseTime(data, unit)
 	if err != nil {
 		return fmt.Errorf("failed to parse DataPointTime, raw: %s, err:%w", string(data), err)
 	}
 
 	*t = DataPointTime{
-		Value:     time.Unix(ts, 0),
+		Value:     ts,
 		Processed: true,
 	}
 	return nil
 }
 
-// DataPointValue represents the Value of a data point, specifically a float32.
-type DataPointValue Value[float32]
+// DataPointValue represents the Value of a data point, decoded to a float64 whatever its encoding.
+type DataPointValue Value[float64]
 
+// UnmarshalJSON decodes the value with DefaultValueCodec.
 func (t *DataPointValue) UnmarshalJSON(data []byte) error {
-	var b []byte
-	if err := json.Unmarshal(data, &b); err != nil {
-		return fmt.Errorf("failed to unmarshal DataPointValue, raw: %s, err:%w", string(data), err)
-	}
+	return t.unmarshal(data, DefaultValueCodec)
+}
 
-	if len(b) != 4 {
-		return fmt.Errorf("invalid data length for DataPointValue, expected 4 bytes, got %d bytes", len(data))
+func (t *DataPointValue) unmarshal(data []byte, codec ValueCodec) error {
+	v, err := codec.Decode(data)
+	if err != nil {
+		return err
 	}
 
-	v := math.Float32frombits(binary.LittleEndian.Uint32(b))
 	*t = DataPointValue{
 		Value:     v,
 		Processed: true,
@@ -93,64 +112,350 @@ func (t *DataPointValue) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// DecodeOptions configures how the data points sent by a data server are decoded. The zero value detects the unit of
+// timestamps from their magnitude and decodes values with DefaultValueCodec.
+type DecodeOptions struct {
+	// TimeUnit is the unit of integer timestamps.
+	TimeUnit TimeUnit
+	// ValueCodec decodes values, nil for DefaultValueCodec.
+	ValueCodec ValueCodec
+}
+
 // DataServerClient is a client for fetching data points from a data server.
 type DataServerClient struct {
-	url    string
-	client *http.Client
+	// name labels the metrics and logs of the client, it is the name of the data source.
+	name    string
+	url     string
+	client  *http.Client
+	retry   RetryPolicy
+	breaker *CircuitBreaker
+	decode  DecodeOptions
+	logger  *slog.Logger
 }
 
-// NewDataServerClient creates a new DataServerClient with the given URL and HTTP client.
-func NewDataServerClient(url string, client *http.Client) *DataServerClient {
+// NewDataServerClient creates a new DataServerClient for the data source name with the given URL, HTTP client, retry
+// policy, circuit breaker and decode options. A nil breaker never stops calling the data server.
+func NewDataServerClient(name string, url string, client *http.Client, retry RetryPolicy, breaker *CircuitBreaker, decode DecodeOptions) *DataServerClient {
 	if client == nil {
 		client = &http.Client{
 			Timeout: 10 * time.Second,
 		}
 	}
 
-	return &DataServerClient{url: url, client: client}
+	return &DataServerClient{
+		name:    name,
+		url:     url,
+		client:  client,
+		retry:   retry,
+		breaker: breaker,
+		decode:  decode,
+		logger:  slog.With("component", "DataServerClient", "source", name),
+	}
 }
 
-// DataPoint fetches a data point from the data server.
-func (ds *DataServerClient) DataPoint(ctx context.Context) (DataPoint, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ds.url, nil)
+// Breaker returns the circuit breaker of the client, nil when it has none.
+func (ds *DataServerClient) Breaker() *CircuitBreaker {
+	return ds.breaker
+}
+
+// Ping checks that the data server is reachable by opening a TCP connection to its host, without requesting a data
+// point. It returns ErrCircuitOpen while the circuit breaker is open.
+func (ds *DataServerClient) Ping(ctx context.Context) error {
+	if ds.breaker != nil && ds.breaker.Status().State == BreakerOpen {
+		return ErrCircuitOpen
+	}
+
+	u, err := url.Parse(ds.url)
 	if err != nil {
-		return DataPoint{}, fmt.Errorf("failed to create request, %w", err)
+		return fmt.Errorf("failed to parse url, %w", err)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
 	}
 
-	resp, err := ds.client.Do(req)
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
 	if err != nil {
-		return DataPoint{}, fmt.Errorf("failed to perform request, %w", err)
+		return fmt.Errorf("failed to connect, %w", err)
 	}
-	defer resp.Body.Close()
+	return conn.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return DataPoint{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+// upstreamError is a failure of the data server to answer a request: a network error or an unexpected status code.
+type upstreamError struct {
+	err       error
+	retryable bool
+}
+
+func (ue *upstreamError) Error() string {
+	return ue.err.Error()
+}
+
+func (ue *upstreamError) Unwrap() error {
+	return ue.err
+}
+
+// DataPoint fetches a data point from the data server. Requests failing with a retryable status code or network error
+// are attempted again following the retry policy, as long as ctx and RetryPolicy.MaxElapsed allow it.
+// While the circuit breaker is open it returns ErrCircuitOpen without contacting the data server.
+func (ds *DataServerClient) DataPoint(ctx context.Context) (_ DataPoint, err error) {
+	ctx, span := tracing.Start(ctx, "DataServerClient.DataPoint", ds.attributes()...)
+	defer func() { tracing.End(span, err) }()
+
+	resp, cancel, err := ds.send(ctx)
+	if err != nil {
+		return DataPoint{}, err
 	}
+	defer cancel()
+	defer resp.Body.Close()
 
 	datapoint, err := ds.decodeDatapointBody(resp.Body)
 	if err != nil {
 		return DataPoint{}, fmt.Errorf("failed to decode datapoint body, %w", err)
 	}
 
-	if valid, err := datapoint.IsValid(); !valid {
-		return DataPoint{}, fmt.Errorf("invalid datapoint received: %v", err)
+	return datapoint, nil
+}
+
+// DataPoints fetches a batch of data points from the data server, sent as a JSON array, as newline delimited JSON
+// objects or as a single object, and decodes them while the response is read, see DecodeDataPoints. Requests are
+// retried and go through the circuit breaker the same way as for DataPoint.
+func (ds *DataServerClient) DataPoints(ctx context.Context) iter.Seq2[DataPoint, error] {
+	return func(yield func(DataPoint, error) bool) {
+		ctx, span := tracing.Start(ctx, "DataServerClient.DataPoints", ds.attributes()...)
+		var failure error
+		count := 0
+		defer func() {
+			span.SetAttributes(attribute.Int("datapoints", count))
+			tracing.End(span, failure)
+		}()
+
+		resp, cancel, err := ds.send(ctx)
+		if err != nil {
+			failure = err
+			yield(DataPoint{}, err)
+			return
+		}
+		defer cancel()
+		defer resp.Body.Close()
+
+		for datapoint, err := range DecodeDataPoints(resp.Body, ds.decode) {
+			if err != nil && !errors.Is(err, ErrInvalidDataPoint) {
+				failure = err
+			}
+			count++
+			if !yield(datapoint, err) {
+				return
+			}
+		}
 	}
+}
 
-	return datapoint, nil
+// attributes returns the span attributes identifying the data server.
+func (ds *DataServerClient) attributes() []attribute.KeyValue {
+	return []attribute.KeyValue{attribute.String("source", ds.name), attribute.String("url.full", ds.url)}
 }
 
-// decodeDatapointBody decodes the response body into a DataPoint.
-func (ds *DataServerClient) decodeDatapointBody(r io.Reader) (DataPoint, error) {
-	datapoint := DataPoint{}
+// DecodeDataPoints decodes the data points read from r, sent as a JSON array, as newline delimited JSON objects or as
+// a single object in the wire format of the data server, with timestamps and values read following opts. Elements are
+// decoded and validated one by one and yielded with a nil error. An invalid element is yielded with an error wrapping
+// ErrInvalidDataPoint and the iteration goes on with the next one. A body failing to decode or to be read ends the
+// iteration with an error wrapping ErrMalformedBody.
+func DecodeDataPoints(r io.Reader, opts DecodeOptions) iter.Seq2[DataPoint, error] {
+	return func(yield func(DataPoint, error) bool) {
+		body := bufio.NewReader(r)
+		array, err := isArray(body)
+		if err != nil {
+			yield(DataPoint{}, fmt.Errorf("%w: %w", ErrMalformedBody, err))
+			return
+		}
+
+		bodyDecoder := json.NewDecoder(body)
+		if array {
+			// consume the opening bracket, the elements are then decoded one at a time
+			if _, err := bodyDecoder.Token(); err != nil {
+				yield(DataPoint{}, fmt.Errorf("%w: %w", ErrMalformedBody, err))
+				return
+			}
+		}
 
-	bodyDecoder := json.NewDecoder(r)
-	err := bodyDecoder.Decode(&datapoint)
+		for !array || bodyDecoder.More() {
+			var raw json.RawMessage
+			if err := bodyDecoder.Decode(&raw); err != nil {
+				if !array && errors.Is(err, io.EOF) {
+					return
+				}
+				yield(DataPoint{}, fmt.Errorf("%w: %w", ErrMalformedBody, err))
+				return
+			}
+
+			datapoint, err := opts.decode(raw)
+			if err != nil && !errors.Is(err, ErrInvalidDataPoint) {
+				err = fmt.Errorf("%w: %v", ErrInvalidDataPoint, err)
+			}
+			if !yield(datapoint, err) {
+				return
+			}
+		}
+	}
+}
+
+// isArray reports whether the next non-whitespace byte of r opens a JSON array, without consuming it.
+func isArray(r *bufio.Reader) (bool, error) {
+	for {
+		b, err := r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b == '[', r.UnreadByte()
+	}
+}
+
+// send requests the data server through the circuit breaker, retrying failed requests following the retry policy.
+// On success the caller must close the response body, then call the returned cancel func.
+func (ds *DataServerClient) send(ctx context.Context) (*http.Response, context.CancelFunc, error) {
+	if ds.breaker == nil {
+		return ds.sendWithRetry(ctx)
+	}
+
+	if err := ds.breaker.Allow(); err != nil {
+		return nil, nil, err
+	}
+
+	resp, cancel, err := ds.sendWithRetry(ctx)
+	var upErr *upstreamError
+	switch {
+	case err != nil && errors.Is(ctx.Err(), context.Canceled):
+		// the caller gave up on the request, a deadline reached is counted as a data server that did not answer
+		ds.breaker.abandon()
+	case errors.As(err, &upErr):
+		ds.breaker.Failure()
+	default:
+		// the data server answered, a body that fails to decode is not an outage
+		ds.breaker.Success()
+	}
+	return resp, cancel, err
+}
+
+// sendWithRetry requests the data server, retrying failed requests following the retry policy. The returned cancel
+// func releases the RetryPolicy.MaxElapsed deadline, which also bounds reading the response body.
+func (ds *DataServerClient) sendWithRetry(ctx context.Context) (*http.Response, context.CancelFunc, error) {
+	cancel := context.CancelFunc(func() {})
+	if ds.retry.MaxElapsed > 0 {
+		ctx, cancel = context.WithTimeout(ctx, ds.retry.MaxElapsed)
+	}
+
+	attempts := ds.retry.attempts()
+	for attempt := 1; ; attempt++ {
+		resp, err := ds.fetch(ctx)
+		if err == nil {
+			return resp, cancel, nil
+		}
+
+		var upErr *upstreamError
+		if !errors.As(err, &upErr) || !upErr.retryable || attempt == attempts {
+			cancel()
+			return nil, nil, err
+		}
+
+		backoff := ds.retry.backoff(attempt - 1)
+		if !wait(ctx, backoff) {
+			cancel()
+			return nil, nil, fmt.Errorf("giving up after %d attempts, next attempt would exceed deadline: %w", attempt, err)
+		}
+		ds.logger.WarnContext(ctx, "Retrying data server request", "attempt", attempt+1, "after", backoff, "error", err)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt+1), attribute.String("error", err.Error())))
+	}
+}
+
+// fetch makes a single request to the data server and returns the response when its status is 200 OK. Failures of
+// the data server to answer are returned as *upstreamError.
+func (ds *DataServerClient) fetch(ctx context.Context) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ds.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request, %w", err)
+	}
+	tracing.Inject(ctx, req.Header)
+
+	start := time.Now()
+	resp, err := ds.client.Do(req)
+	metrics.UpstreamRequestDuration.WithLabelValues(ds.name).Observe(time.Since(start).Seconds())
 	if err != nil {
+		metrics.UpstreamResponses.WithLabelValues(ds.name, "error").Inc()
+		return nil, &upstreamError{
+			err:       fmt.Errorf("failed to perform request, %w", err),
+			retryable: ds.retry.retryableError(ctx, err),
+		}
+	}
+
+	metrics.UpstreamResponses.WithLabelValues(ds.name, strconv.Itoa(resp.StatusCode)).Inc()
+	if resp.StatusCode != http.StatusOK {
+		// drain the body so the connection can be reused by the next attempt
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, &upstreamError{
+			err:       fmt.Errorf("unexpected status code: %d", resp.StatusCode),
+			retryable: ds.retry.retryableStatus(resp.StatusCode),
+		}
+	}
+
+	return resp, nil
+}
+
+// decodeDatapointBody decodes the response body into a DataPoint, reading timestamps and values with the decode
+// options of the data server.
+func (ds *DataServerClient) decodeDatapointBody(r io.Reader) (DataPoint, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
 		return DataPoint{}, fmt.Errorf("failed to decode response body, %w", err)
 	}
 
+	return ds.decode.decode(raw)
+}
+
+// decode decodes a single JSON object into a DataPoint and checks that it is valid.
+func (opts DecodeOptions) decode(data []byte) (DataPoint, error) {
+	// the raw time and value shadow the ones of the data point so that they are decoded with the options of the client
+	body := struct {
+		DataPoint
+		Time  json.RawMessage `json:"time"`
+		Value json.RawMessage `json:"value"`
+	}{}
+
+	if err := json.Unmarshal(data, &body); err != nil {
+		return DataPoint{}, fmt.Errorf("failed to decode response body, %w", err)
+	}
+
+	datapoint := body.DataPoint
+	if body.Time != nil {
+		if err := datapoint.Time.unmarshal(body.Time, opts.TimeUnit); err != nil {
+			return DataPoint{}, fmt.Errorf("failed to decode response body, %w", err)
+		}
+	}
+	if body.Value != nil {
+		codec := opts.ValueCodec
+		if codec == nil {
+			codec = DefaultValueCodec
+		}
+		if err := datapoint.Value.unmarshal(body.Value, codec); err != nil {
+			return DataPoint{}, fmt.Errorf("failed to decode response body, %w", err)
+		}
+	}
+
 	if valid, err := datapoint.IsValid(); !valid {
-		return DataPoint{}, fmt.Errorf("invalid datapoint received: %v", err)
+		return DataPoint{}, fmt.Errorf("%w: %v", ErrInvalidDataPoint, err)
 	}
 
 	return datapoint, nil
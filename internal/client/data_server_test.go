@@ -169,7 +169,7 @@ func TestDataPointValueUnmarshalJSON(t *testing.T) {
 		name      string
 		input     []byte
 		expectErr bool
-		expectVal float32
+		expectVal float64
 	}{
 		{
 			name:      "valid float32 bytes",
@@ -229,7 +229,7 @@ func TestDataPointValueUnmarshalJSON(t *testing.T) {
 					t.Errorf("expected Processed=true, got false")
 				}
 				// Allow for small floating point errors
-				if math.Abs(float64(dpv.Value-tt.expectVal)) > 1e-5 {
+				if math.Abs(dpv.Value-tt.expectVal) > 1e-5 {
 					t.Errorf("expected Value %f, got %f", tt.expectVal, dpv.Value)
 				}
 			}
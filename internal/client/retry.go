@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// Network error kinds accepted in RetryPolicy.RetryableNetworkErrors.
+const (
+	// NetworkErrorTimeout is a request that timed out before the response was received.
+	NetworkErrorTimeout = "timeout"
+	// NetworkErrorConnectionRefused is a connection refused by the data server.
+	NetworkErrorConnectionRefused = "connection_refused"
+	// NetworkErrorConnectionReset is a connection reset or closed by the data server while the request was in flight.
+	NetworkErrorConnectionReset = "connection_reset"
+	// NetworkErrorDNS is a failure to resolve the data server host.
+	NetworkErrorDNS = "dns"
+)
+
+// RetryPolicy decides whether and when a failed request to the data server is attempted again.
+// The zero value makes a single attempt.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// BaseBackoff is the wait before the first retry, it doubles after every retry.
+	BaseBackoff time.Duration
+	// MaxBackoff caps the wait between two attempts.
+	MaxBackoff time.Duration
+	// Jitter is the fraction, between 0 and 1, of every wait that is randomized so that collectors do not retry in step.
+	Jitter float64
+	// RetryableStatusCodes are the response status codes that are retried.
+	RetryableStatusCodes []int
+	// RetryableNetworkErrors are the kinds of network errors that are retried, NetworkError* constants.
+	RetryableNetworkErrors []string
+	// MaxElapsed bounds the time spent on a call including all its attempts and waits, 0 leaves it unbounded. The
+	// deadline of the context of the call bounds it the same way.
+	MaxElapsed time.Duration
+}
+
+// attempts returns the number of attempts allowed by the policy, at least one.
+func (rp RetryPolicy) attempts() int {
+	return max(rp.MaxAttempts, 1)
+}
+
+// backoff returns the wait before retry number retry, starting at 0.
+func (rp RetryPolicy) backoff(retry int) time.Duration {
+	wait := rp.BaseBackoff
+	for range retry {
+		if rp.MaxBackoff > 0 && wait >= rp.MaxBackoff {
+			break
+		}
+		wait *= 2
+	}
+	if rp.MaxBackoff > 0 {
+		wait = min(wait, rp.MaxBackoff)
+	}
+
+	jitter := min(max(rp.Jitter, 0), 1)
+	return wait - time.Duration(jitter*rand.Float64()*float64(wait))
+}
+
+// retryableStatus tells whether a response with status code is retried.
+func (rp RetryPolicy) retryableStatus(code int) bool {
+	return slices.Contains(rp.RetryableStatusCodes, code)
+}
+
+// retryableError tells whether a request that failed with err before a response was received is retried.
+// Requests cancelled by the caller are never retried, a deadline reached is a timeout.
+func (rp RetryPolicy) retryableError(ctx context.Context, err error) bool {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return false
+	}
+
+	kind := networkErrorKind(err)
+	return kind != "" && slices.Contains(rp.RetryableNetworkErrors, kind)
+}
+
+// networkErrorKind classifies err as one of the NetworkError* constants, it returns an empty string for other errors.
+func networkErrorKind(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		return NetworkErrorDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return NetworkErrorConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return NetworkErrorConnectionReset
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return NetworkErrorTimeout
+	default:
+		return ""
+	}
+}
+
+// wait sleeps for d, it returns false without waiting when the deadline of ctx is reached first.
+func wait(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
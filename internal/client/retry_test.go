@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyServer returns a data server failing with status the first failures requests, then returning a data point.
+func newFlakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		fmt.Fprintf(w, `{"time":%d,"value":%s,"tags":["tag1"]}`, time.Now().Unix(), toJSONByteArray(float32ToBytes(1.5)))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+// TestDataServerClient_Retry tests that retryable status codes are retried until an attempt succeeds.
+func TestDataServerClient_Retry(t *testing.T) {
+	server, calls := newFlakyServer(t, 2, http.StatusServiceUnavailable)
+	client := NewDataServerClient("test", server.URL, nil, RetryPolicy{
+		MaxAttempts:          3,
+		BaseBackoff:          time.Millisecond,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+	}, nil, DecodeOptions{})
+
+	dp, err := client.DataPoint(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dp.Value.Value != 1.5 {
+		t.Errorf("expected Value 1.5, got %f", dp.Value.Value)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+}
+
+// TestDataServerClient_RetryExhausted tests that the last error is returned once all attempts failed.
+func TestDataServerClient_RetryExhausted(t *testing.T) {
+	server, calls := newFlakyServer(t, 5, http.StatusBadGateway)
+	client := NewDataServerClient("test", server.URL, nil, RetryPolicy{
+		MaxAttempts:          2,
+		BaseBackoff:          time.Millisecond,
+		RetryableStatusCodes: []int{http.StatusBadGateway},
+	}, nil, DecodeOptions{})
+
+	_, err := client.DataPoint(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "unexpected status code: 502") {
+		t.Errorf("expected status code error, got %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 attempts, got %d", got)
+	}
+}
+
+// TestDataServerClient_NoRetry tests that status codes that are not retryable fail on the first attempt.
+func TestDataServerClient_NoRetry(t *testing.T) {
+	server, calls := newFlakyServer(t, 1, http.StatusBadRequest)
+	client := NewDataServerClient("test", server.URL, nil, RetryPolicy{
+		MaxAttempts:          3,
+		BaseBackoff:          time.Millisecond,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+	}, nil, DecodeOptions{})
+
+	if _, err := client.DataPoint(context.Background()); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected 1 attempt, got %d", got)
+	}
+}
+
+// TestDataServerClient_RetryMaxElapsed tests that no retry starts when its backoff would exceed MaxElapsed.
+func TestDataServerClient_RetryMaxElapsed(t *testing.T) {
+	server, calls := newFlakyServer(t, 5, http.StatusServiceUnavailable)
+	client := NewDataServerClient("test", server.URL, nil, RetryPolicy{
+		MaxAttempts:          5,
+		BaseBackoff:          time.Second,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+		MaxElapsed:           100 * time.Millisecond,
+	}, nil, DecodeOptions{})
+
+	start := time.Now()
+	_, err := client.DataPoint(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "giving up after 1 attempts") {
+		t.Errorf("expected give up error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("expected to give up without waiting, took %s", elapsed)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected 1 attempt, got %d", got)
+	}
+}
+
+// countingTransport counts the requests sent through the default transport.
+type countingTransport struct {
+	calls atomic.Int32
+}
+
+func (ct *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ct.calls.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// TestDataServerClient_RetryNetworkError tests that refused connections are retried only when configured.
+func TestDataServerClient_RetryNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	for _, tt := range []struct {
+		name      string
+		retryable []string
+		expected  int32
+	}{
+		{name: "retryable", retryable: []string{NetworkErrorConnectionRefused}, expected: 3},
+		{name: "not retryable", retryable: []string{NetworkErrorTimeout}, expected: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &countingTransport{}
+			_, err := NewDataServerClient("test", url, &http.Client{Transport: transport}, RetryPolicy{
+				MaxAttempts:            3,
+				BaseBackoff:            time.Millisecond,
+				RetryableNetworkErrors: tt.retryable,
+			}, nil, DecodeOptions{}).DataPoint(context.Background())
+			if kind := networkErrorKind(err); kind != NetworkErrorConnectionRefused {
+				t.Errorf("expected %q error, got %q: %v", NetworkErrorConnectionRefused, kind, err)
+			}
+			if got := transport.calls.Load(); got != tt.expected {
+				t.Errorf("expected %d attempts, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestRetryPolicyBackoff tests that backoffs double up to MaxBackoff and stay within the jitter range.
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for retry, want := range expected {
+		if got := policy.backoff(retry); got != want {
+			t.Errorf("retry %d: expected backoff %s, got %s", retry, want, got)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for range 100 {
+		if got := policy.backoff(3); got < 400*time.Millisecond || got > 800*time.Millisecond {
+			t.Errorf("expected jittered backoff between 400ms and 800ms, got %s", got)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TimeUnit is the unit of the integer timestamps sent by a data server.
+type TimeUnit string
+
+const (
+	// TimeUnitAuto detects the unit of every timestamp from its magnitude, see detectTimeUnit.
+	TimeUnitAuto TimeUnit = "auto"
+	// TimeUnitSecond is a unix timestamp in seconds.
+	TimeUnitSecond TimeUnit = "s"
+	// TimeUnitMillisecond is a unix timestamp in milliseconds.
+	TimeUnitMillisecond TimeUnit = "ms"
+	// TimeUnitMicrosecond is a unix timestamp in microseconds.
+	TimeUnitMicrosecond TimeUnit = "us"
+	// TimeUnitNanosecond is a unix timestamp in nanoseconds.
+	TimeUnitNanosecond TimeUnit = "ns"
+)
+
+// ParseTimeUnit returns the TimeUnit named s, an empty string is TimeUnitAuto.
+func ParseTimeUnit(s string) (TimeUnit, error) {
+	switch unit := TimeUnit(s); unit {
+	case "":
+		return TimeUnitAuto, nil
+	case TimeUnitAuto, TimeUnitSecond, TimeUnitMillisecond, TimeUnitMicrosecond, TimeUnitNanosecond:
+		return unit, nil
+	default:
+		return "", fmt.Errorf("unknown time unit %q, expected one of auto, s, ms, us or ns", s)
+	}
+}
+
+// detectTimeUnit returns the unit of the unix timestamp ts from its magnitude. Every unit is assumed to cover the
+// dates from 1973 to 5138, e.g. 1700000000 is in seconds and 1700000000000 in milliseconds.
+func detectTimeUnit(ts int64) TimeUnit {
+	if ts < 0 {
+		ts = -ts
+	}
+
+	switch {
+	case ts < 1e11:
+		return TimeUnitSecond
+	case ts < 1e14:
+		return TimeUnitMillisecond
+	case ts < 1e17:
+		return TimeUnitMicrosecond
+	default:
+		return TimeUnitNanosecond
+	}
+}
+
+// parseTime parses a timestamp sent by a data server: an integer unix timestamp in unit, or an RFC3339 string with
+// any number of fractional digits. An empty or TimeUnitAuto unit detects the unit of integers from their magnitude.
+func parseTime(data []byte, unit TimeUnit) (time.Time, error) {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return time.Time{}, err
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	}
+
+	ts, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if unit == "" || unit == TimeUnitAuto {
+		unit = detectTimeUnit(ts)
+	}
+	switch unit {
+	case TimeUnitMillisecond:
+		return time.UnixMilli(ts), nil
+	case TimeUnitMicrosecond:
+		return time.UnixMicro(ts), nil
+	case TimeUnitNanosecond:
+		return time.Unix(0, ts), nil
+	default:
+		return time.Unix(ts, 0), nil
+	}
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDataPointTimeUnmarshalJSON tests parsing integer timestamps of every unit and RFC3339 strings.
+func TestDataPointTimeUnmarshalJSON(t *testing.T) {
+	want := time.Date(2023, 11, 14, 22, 13, 20, 123456789, time.UTC)
+	tests := []struct {
+		name      string
+		input     string
+		unit      TimeUnit
+		expectVal time.Time
+		expectErr bool
+	}{
+		{name: "seconds", input: `1700000000`, expectVal: want.Truncate(time.Second)},
+		{name: "milliseconds", input: `1700000000123`, expectVal: want.Truncate(time.Millisecond)},
+		{name: "microseconds", input: `1700000000123456`, expectVal: want.Truncate(time.Microsecond)},
+		{name: "nanoseconds", input: `1700000000123456789`, expectVal: want},
+		{name: "RFC3339", input: `"2023-11-14T22:13:20Z"`, expectVal: want.Truncate(time.Second)},
+		{name: "RFC3339 with fraction", input: `"2023-11-14T23:13:20.123456789+01:00"`, expectVal: want},
+		{name: "explicit unit", input: `1700000000123`, unit: TimeUnitMicrosecond, expectVal: time.UnixMicro(1700000000123)},
+		{name: "explicit seconds", input: `1700000000123`, unit: TimeUnitSecond, expectVal: time.Unix(1700000000123, 0)},
+		{name: "float", input: `1700000000.5`, expectErr: true},
+		{name: "invalid string", input: `"yesterday"`, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dpt DataPointTime
+			var err error
+			if tt.unit == "" {
+				err = dpt.UnmarshalJSON([]byte(tt.input))
+			} else {
+				err = dpt.unmarshal([]byte(tt.input), tt.unit)
+			}
+
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !dpt.Processed {
+				t.Errorf("expected Processed=true, got false")
+			}
+			if !dpt.Value.Equal(tt.expectVal) {
+				t.Errorf("expected Value %s, got %s", tt.expectVal, dpt.Value)
+			}
+		})
+	}
+}
+
+// TestDecodeDatapointBody_TimeUnit tests that the time unit of the client is used for integer timestamps.
+func TestDecodeDatapointBody_TimeUnit(t *testing.T) {
+	body := `{"time":1700000000,"value":` + toJSONByteArray(float32ToBytes(1.5)) + `,"tags":[]}`
+	client := &DataServerClient{decode: DecodeOptions{TimeUnit: TimeUnitMillisecond}}
+
+	dp, err := client.decodeDatapointBody(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := time.UnixMilli(1700000000); !dp.Time.Value.Equal(want) {
+		t.Errorf("expected time %s, got %s", want, dp.Time.Value)
+	}
+}
+
+// TestParseTimeUnit tests that unknown time units are rejected.
+func TestParseTimeUnit(t *testing.T) {
+	if unit, err := ParseTimeUnit(""); err != nil || unit != TimeUnitAuto {
+		t.Errorf("expected auto for empty unit, got %q, %v", unit, err)
+	}
+	if unit, err := ParseTimeUnit("ms"); err != nil || unit != TimeUnitMillisecond {
+		t.Errorf("expected ms, got %q, %v", unit, err)
+	}
+	if _, err := ParseTimeUnit("minutes"); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
@@ -2,16 +2,28 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"oc-data-be-challenge/internal/client"
 	"oc-data-be-challenge/internal/usecase"
 	"time"
 )
 
-func NewDataServerCollector(datapointUseCase *usecase.DataPointUseCase, interval time.Duration) *PeriodicTrigger {
-	return NewPeriodicTrigger(
-		"DataServerCollector",
+// NewDataServerCollector returns a trigger collecting a data point from source every interval. A collection, including
+// all its retries, is bounded by the current interval of the trigger so that it never overlaps the next one.
+func NewDataServerCollector(datapointUseCase *usecase.DataPointUseCase, source usecase.DataSource, interval time.Duration) *PeriodicTrigger {
+	var trigger *PeriodicTrigger
+	trigger = NewPeriodicTrigger(
+		"DataServerCollector/"+source.Name,
 		func(ctx context.Context) error {
-			err := datapointUseCase.Collect(ctx)
+			ctx, cancel := context.WithTimeout(ctx, trigger.Status().Interval)
+			defer cancel()
+
+			err := datapointUseCase.Collect(ctx, source)
+			if errors.Is(err, client.ErrCircuitOpen) {
+				// the outage is reported once by the circuit breaker, not on every poll
+				return nil
+			}
 			if err != nil {
 				return fmt.Errorf("failed to collect data point: %w", err)
 			}
@@ -19,4 +31,5 @@ func NewDataServerCollector(datapointUseCase *usecase.DataPointUseCase, interval
 		},
 		interval,
 	)
+	return trigger
 }
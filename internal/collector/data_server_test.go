@@ -0,0 +1,44 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"oc-data-be-challenge/internal/client"
+	"oc-data-be-challenge/internal/data/repository"
+	"oc-data-be-challenge/internal/usecase"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDataServerCollector_Deadline tests that a collection is bounded by the current interval of the collector, so that
+// a data server that does not answer never delays the next one and is counted as a failure by its circuit breaker.
+func TestDataServerCollector_Deadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	source := usecase.DataSource{
+		Name:   "north",
+		Client: client.NewDataServerClient("north", server.URL, nil, client.RetryPolicy{}, client.NewCircuitBreaker("north", client.BreakerOptions{}), client.DecodeOptions{}),
+	}
+	uc := usecase.NewDataPointUseCase(repository.NewMemoryDataPoint(), []usecase.DataSource{source}, nil, nil, nil, "test")
+	trigger := NewDataServerCollector(uc, source, 50*time.Millisecond)
+	go trigger.Start()
+	defer trigger.Stop()
+	require.Eventually(t, func() bool { return trigger.Status().Runs >= 1 }, time.Second, time.Millisecond)
+	assert.ErrorIs(t, trigger.Status().LastError, context.DeadlineExceeded)
+	// a collection reaching its deadline is a data server that did not answer
+	assert.Positive(t, source.Client.Breaker().Status().ConsecutiveFailures)
+
+	// the deadline follows the interval changed at runtime
+	require.NoError(t, trigger.Pause())
+	require.NoError(t, trigger.SetInterval(30*time.Millisecond))
+	started := time.Now()
+	assert.ErrorIs(t, trigger.TriggerNow(context.Background()), context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), time.Second)
+}
@@ -2,12 +2,45 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"oc-data-be-challenge/internal/metrics"
+	"oc-data-be-challenge/internal/tracing"
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// TriggerState is the state of a PeriodicTrigger.
+type TriggerState string
+
+const (
+	// TriggerStopped is the state of a trigger that is not started.
+	TriggerStopped TriggerState = "stopped"
+	// TriggerRunning is the state of a started trigger calling its function every interval.
+	TriggerRunning TriggerState = "running"
+	// TriggerPaused is the state of a started trigger skipping its ticks until it is resumed.
+	TriggerPaused TriggerState = "paused"
 )
 
+// ErrTriggerStopped is returned when controlling a trigger that is not started.
+var ErrTriggerStopped = errors.New("trigger is not started")
+
+// TriggerStatus is a snapshot of a PeriodicTrigger.
+type TriggerStatus struct {
+	State    TriggerState
+	Interval time.Duration
+	// LastRunAt is the start time of the last run, zero before the first one.
+	LastRunAt time.Time
+	// LastError is the error of the last run, nil when it succeeded.
+	LastError error
+	// Runs is the number of runs since the trigger was created, ticks and TriggerNow alike.
+	Runs int64
+}
+
 type PeriodicTrigger struct {
+	name                 string
 	interval             time.Duration
 	stopCh               chan struct{}
 	startOnce            *sync.Once
@@ -16,13 +49,28 @@ type PeriodicTrigger struct {
 	triggerCtxCancelFunc context.CancelFunc
 	triggerFn            func(ctx context.Context) error
 	logger               *slog.Logger
+
+	// intervalCh notifies the running trigger that interval changed.
+	intervalCh chan struct{}
+	// running holds a token during a run to serialize the runs, a run triggered now waits for the one of the current
+	// tick.
+	running chan struct{}
+	// mu guards the fields above replaced by reset, interval, state and the fields of the last run.
+	mu        sync.Mutex
+	state     TriggerState
+	lastRunAt time.Time
+	lastError error
+	runs      int64
 }
 
 func NewPeriodicTrigger(name string, triggerFn func(ctx context.Context) error, interval time.Duration) *PeriodicTrigger {
 	periodicTrigger := &PeriodicTrigger{
-		triggerFn: triggerFn,
-		interval:  interval,
-		logger:    slog.With("component", "PeriodicTrigger", "name", name),
+		name:       name,
+		triggerFn:  triggerFn,
+		interval:   interval,
+		intervalCh: make(chan struct{}, 1),
+		running:    make(chan struct{}, 1),
+		logger:     slog.With("component", "PeriodicTrigger", "name", name),
 	}
 
 	periodicTrigger.reset()
@@ -30,10 +78,13 @@ func NewPeriodicTrigger(name string, triggerFn func(ctx context.Context) error,
 }
 
 func (pt *PeriodicTrigger) reset() {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
 	pt.stopCh = make(chan struct{}, 1)
 	pt.startOnce = &sync.Once{}
 	pt.stopOnce = &sync.Once{}
 	pt.triggerCtx, pt.triggerCtxCancelFunc = context.WithCancel(context.Background())
+	pt.state = TriggerStopped
 }
 
 func (pt *PeriodicTrigger) Start() {
@@ -41,21 +92,26 @@ func (pt *PeriodicTrigger) Start() {
 }
 
 func (pt *PeriodicTrigger) start() {
-	pt.logger.InfoContext(pt.triggerCtx, "PeriodicTrigger started", "interval", pt.interval)
-	err := pt.triggerFn(pt.triggerCtx)
+	pt.mu.Lock()
+	pt.state = TriggerRunning
+	interval, ctx, cancel, stopCh := pt.interval, pt.triggerCtx, pt.triggerCtxCancelFunc, pt.stopCh
+	pt.mu.Unlock()
+
+	pt.logger.InfoContext(ctx, "PeriodicTrigger started", "interval", interval)
+	err := pt.run(ctx)
 	if err != nil {
-		pt.logger.ErrorContext(pt.triggerCtx, "PeriodicTrigger initial collection error", "error", err)
+		pt.logger.ErrorContext(ctx, "PeriodicTrigger initial collection error", "error", err)
 	}
 
-	ticker := time.NewTicker(pt.interval)
+	ticker := time.NewTicker(interval)
 	doneCh := make(chan struct{})
 
 	// Goroutine to listen for stop signal
 	go func() {
 		select {
-		case <-pt.stopCh:
+		case <-stopCh:
 			pt.logger.Info("PeriodicTrigger stopping")
-			pt.triggerCtxCancelFunc()
+			cancel()
 			ticker.Stop()
 			doneCh <- struct{}{}
 
@@ -65,12 +121,18 @@ func (pt *PeriodicTrigger) start() {
 	for {
 		select {
 		case <-ticker.C:
+			if pt.Status().State == TriggerPaused {
+				pt.logger.Debug("PeriodicTrigger tick skipped, paused")
+				continue
+			}
 			pt.logger.Debug("PeriodicTrigger tick")
-			err := pt.triggerFn(pt.triggerCtx)
+			err := pt.run(ctx)
 			if err != nil {
 				pt.logger.Error("PeriodicTrigger collection error", "error", err)
 				continue
 			}
+		case <-pt.intervalCh:
+			ticker.Reset(pt.Status().Interval)
 		case <-doneCh:
 			goto exitFor
 		}
@@ -78,12 +140,124 @@ func (pt *PeriodicTrigger) start() {
 exitFor:
 }
 
+// run calls the trigger function once with ctx, in a span of its own, and records the run in the trigger status and
+// metrics. It returns the error of ctx without running when ctx is done while the run in progress is waited for.
+func (pt *PeriodicTrigger) run(ctx context.Context) error {
+	select {
+	case pt.running <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-pt.running }()
+
+	startedAt := time.Now()
+	metrics.TriggerTicks.WithLabelValues(pt.name).Inc()
+	ctx, span := tracing.Start(ctx, "PeriodicTrigger.run", attribute.String("trigger", pt.name))
+	err := pt.triggerFn(ctx)
+	tracing.End(span, err)
+
+	result := metrics.ResultSuccess
+	if err != nil {
+		result = metrics.ResultFailure
+	}
+	metrics.TriggerRuns.WithLabelValues(pt.name, result).Inc()
+
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.lastRunAt = startedAt
+	pt.lastError = err
+	pt.runs++
+	return err
+}
+
+// Pause skips the ticks of the trigger until Resume is called, a run in progress is not interrupted. It returns
+// ErrTriggerStopped when the trigger is not started.
+func (pt *PeriodicTrigger) Pause() error {
+	return pt.transition(TriggerPaused)
+}
+
+// Resume runs the trigger again on the next tick after Pause. It returns ErrTriggerStopped when the trigger is not
+// started.
+func (pt *PeriodicTrigger) Resume() error {
+	return pt.transition(TriggerRunning)
+}
+
+// transition moves a started trigger to state.
+func (pt *PeriodicTrigger) transition(state TriggerState) error {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if pt.state == TriggerStopped {
+		return ErrTriggerStopped
+	}
+	if pt.state != state {
+		pt.logger.Info("PeriodicTrigger state changed", "from", pt.state, "to", state)
+		pt.state = state
+	}
+	return nil
+}
+
+// TriggerNow runs the trigger function immediately with ctx, paused or not, and returns its error once it is done. A
+// run in progress is waited for first, as long as ctx is not done. It returns ErrTriggerStopped when the trigger is not
+// started.
+func (pt *PeriodicTrigger) TriggerNow(ctx context.Context) error {
+	pt.mu.Lock()
+	state, triggerCtx := pt.state, pt.triggerCtx
+	pt.mu.Unlock()
+	if state == TriggerStopped {
+		return ErrTriggerStopped
+	}
+
+	// the run is cancelled when the trigger stops too, like the runs of the ticks
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(triggerCtx, cancel)
+	defer stop()
+	return pt.run(ctx)
+}
+
+// SetInterval changes the interval of the trigger, the next tick is one interval away from the change when the trigger
+// is started.
+func (pt *PeriodicTrigger) SetInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+
+	pt.mu.Lock()
+	pt.logger.Info("PeriodicTrigger interval changed", "from", pt.interval, "to", interval)
+	pt.interval = interval
+	pt.mu.Unlock()
+
+	// a change already pending is applied with the latest interval
+	select {
+	case pt.intervalCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Status returns a snapshot of the trigger.
+func (pt *PeriodicTrigger) Status() TriggerStatus {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	return TriggerStatus{
+		State:     pt.state,
+		Interval:  pt.interval,
+		LastRunAt: pt.lastRunAt,
+		LastError: pt.lastError,
+		Runs:      pt.runs,
+	}
+}
+
 func (pt *PeriodicTrigger) Stop() {
 	pt.stopOnce.Do(pt.stop)
 }
 
 func (pt *PeriodicTrigger) stop() {
-	pt.stopCh <- struct{}{}
-	close(pt.stopCh)
+	pt.mu.Lock()
+	stopCh := pt.stopCh
+	pt.mu.Unlock()
+
+	stopCh <- struct{}{}
+	close(stopCh)
 	pt.reset()
 }
@@ -298,3 +298,129 @@ func TestPeriodicTrigger_LongRunningTriggerFunction(t *testing.T) {
 	count := callCount.Load()
 	assert.GreaterOrEqual(t, count, int32(2))
 }
+
+// TestPeriodicTrigger_PauseResume tests that ticks are skipped while the trigger is paused
+func TestPeriodicTrigger_PauseResume(t *testing.T) {
+	callCount := atomic.Int32{}
+	triggerFn := func(ctx context.Context) error {
+		callCount.Add(1)
+		return nil
+	}
+
+	pt := NewPeriodicTrigger("test-trigger", triggerFn, 20*time.Millisecond)
+	go pt.Start()
+	defer pt.Stop()
+	time.Sleep(50 * time.Millisecond)
+
+	require.NoError(t, pt.Pause())
+	assert.Equal(t, TriggerPaused, pt.Status().State)
+	// let a run in progress finish before counting
+	time.Sleep(30 * time.Millisecond)
+	pausedCount := callCount.Load()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, pausedCount, callCount.Load(), "No call should happen while paused")
+
+	require.NoError(t, pt.Resume())
+	assert.Equal(t, TriggerRunning, pt.Status().State)
+	time.Sleep(100 * time.Millisecond)
+	assert.Greater(t, callCount.Load(), pausedCount, "Calls should resume after resume")
+}
+
+// TestPeriodicTrigger_TriggerNow tests that a run triggered now is recorded in the status, even while paused
+func TestPeriodicTrigger_TriggerNow(t *testing.T) {
+	failing := atomic.Bool{}
+	triggerFn := func(ctx context.Context) error {
+		if failing.Load() {
+			return errors.New("test error")
+		}
+		return nil
+	}
+
+	pt := NewPeriodicTrigger("test-trigger", triggerFn, time.Hour)
+	go pt.Start()
+	defer pt.Stop()
+	require.Eventually(t, func() bool { return pt.Status().Runs == 1 }, time.Second, 10*time.Millisecond)
+	require.NoError(t, pt.Pause())
+
+	failing.Store(true)
+	before := time.Now()
+	assert.EqualError(t, pt.TriggerNow(context.Background()), "test error")
+
+	status := pt.Status()
+	assert.Equal(t, TriggerPaused, status.State)
+	assert.Equal(t, int64(2), status.Runs)
+	assert.EqualError(t, status.LastError, "test error")
+	assert.False(t, status.LastRunAt.Before(before))
+
+	failing.Store(false)
+	require.NoError(t, pt.TriggerNow(context.Background()))
+	assert.NoError(t, pt.Status().LastError)
+}
+
+// TestPeriodicTrigger_TriggerNowContext tests that a run triggered now is cancelled with its context, and that it gives
+// up waiting for the run in progress once its context is done.
+func TestPeriodicTrigger_TriggerNowContext(t *testing.T) {
+	blocking := atomic.Bool{}
+	triggerFn := func(ctx context.Context) error {
+		if !blocking.Load() {
+			return nil
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	pt := NewPeriodicTrigger("test-trigger", triggerFn, time.Hour)
+	go pt.Start()
+	defer pt.Stop()
+	require.Eventually(t, func() bool { return pt.Status().Runs == 1 }, time.Second, 10*time.Millisecond)
+	blocking.Store(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pt.TriggerNow(ctx), context.DeadlineExceeded)
+	assert.Equal(t, int64(2), pt.Status().Runs)
+
+	inProgress, cancelInProgress := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- pt.TriggerNow(inProgress) }()
+	require.Eventually(t, func() bool { return len(pt.running) == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pt.TriggerNow(ctx), context.DeadlineExceeded)
+	cancelInProgress()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, int64(3), pt.Status().Runs, "the run waiting for the one in progress gave up")
+}
+
+// TestPeriodicTrigger_SetInterval tests that a new interval applies to the running trigger
+func TestPeriodicTrigger_SetInterval(t *testing.T) {
+	callCount := atomic.Int32{}
+	triggerFn := func(ctx context.Context) error {
+		callCount.Add(1)
+		return nil
+	}
+
+	pt := NewPeriodicTrigger("test-trigger", triggerFn, time.Hour)
+	go pt.Start()
+	defer pt.Stop()
+	require.Eventually(t, func() bool { return callCount.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, pt.SetInterval(20*time.Millisecond))
+	assert.Equal(t, 20*time.Millisecond, pt.Status().Interval)
+	time.Sleep(110 * time.Millisecond)
+	assert.GreaterOrEqual(t, callCount.Load(), int32(4), "Expected periodic calls at the new interval")
+
+	assert.Error(t, pt.SetInterval(0))
+}
+
+// TestPeriodicTrigger_ControlStopped tests that a trigger that is not started cannot be paused, resumed or triggered
+func TestPeriodicTrigger_ControlStopped(t *testing.T) {
+	pt := NewPeriodicTrigger("test-trigger", func(ctx context.Context) error { return nil }, time.Hour)
+
+	assert.Equal(t, TriggerStopped, pt.Status().State)
+	assert.ErrorIs(t, pt.Pause(), ErrTriggerStopped)
+	assert.ErrorIs(t, pt.Resume(), ErrTriggerStopped)
+	assert.ErrorIs(t, pt.TriggerNow(context.Background()), ErrTriggerStopped)
+	assert.Equal(t, int64(0), pt.Status().Runs)
+}
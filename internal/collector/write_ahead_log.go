@@ -0,0 +1,24 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"oc-data-be-challenge/internal/usecase"
+	"time"
+)
+
+// NewWriteAheadLogDrainer returns a trigger writing the data points queued in the write-ahead log to the repository
+// every interval. A failed drain is retried on the next run, the queued data points are kept until they are written.
+func NewWriteAheadLogDrainer(datapointUseCase *usecase.DataPointUseCase, interval time.Duration) *PeriodicTrigger {
+	return NewPeriodicTrigger(
+		"WriteAheadLogDrainer",
+		func(ctx context.Context) error {
+			err := datapointUseCase.Drain(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to drain write-ahead log: %w", err)
+			}
+			return nil
+		},
+		interval,
+	)
+}
@@ -0,0 +1,100 @@
+package dto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AggregateFunc is the function reducing the values of a window to a single value.
+type AggregateFunc string
+
+const (
+	AggregateMean  AggregateFunc = "mean"
+	AggregateMin   AggregateFunc = "min"
+	AggregateMax   AggregateFunc = "max"
+	AggregateSum   AggregateFunc = "sum"
+	AggregateCount AggregateFunc = "count"
+	// AggregateFirst is the value of the earliest data point of the window.
+	AggregateFirst AggregateFunc = "first"
+	// AggregateLast is the value of the latest data point of the window.
+	AggregateLast AggregateFunc = "last"
+	// AggregatePercentile is the value below which Aggregation.Percentile of the values of the window fall.
+	AggregatePercentile AggregateFunc = "percentile"
+)
+
+const (
+	// MaxAggregateWindows bounds the number of windows the time range of an aggregation may cover.
+	MaxAggregateWindows = 10000
+	// MinOpenAggregateWindow is the narrowest window of an aggregation over a time range without start.
+	MinOpenAggregateWindow = time.Minute
+)
+
+// Aggregation groups data points in fixed windows aligned on the unix epoch and reduces each window to one value.
+type Aggregation struct {
+	Window time.Duration
+	Func   AggregateFunc
+	// Percentile is the fraction, between 0 and 1, computed by AggregatePercentile.
+	Percentile float64
+}
+
+// ParseAggregation returns the aggregation over window for fn. fn is one of mean, min, max, sum, count, first, last,
+// or a percentile written "p" followed by a number between 0 and 100, e.g. "p95" or "p99.9".
+func ParseAggregation(window time.Duration, fn string) (Aggregation, error) {
+	if window <= 0 {
+		return Aggregation{}, fmt.Errorf("window must be positive, got %s", window)
+	}
+
+	switch f := AggregateFunc(fn); f {
+	case AggregateMean, AggregateMin, AggregateMax, AggregateSum, AggregateCount, AggregateFirst, AggregateLast:
+		return Aggregation{Window: window, Func: f}, nil
+	}
+
+	if p, ok := strings.CutPrefix(fn, "p"); ok {
+		percentile, err := strconv.ParseFloat(p, 64)
+		if err == nil && percentile >= 0 && percentile <= 100 {
+			return Aggregation{Window: window, Func: AggregatePercentile, Percentile: percentile / 100}, nil
+		}
+	}
+
+	return Aggregation{}, fmt.Errorf("unknown aggregate function %q", fn)
+}
+
+// WindowStart returns the start of the window t belongs to.
+func (a Aggregation) WindowStart(t time.Time) time.Time {
+	ns, window := t.UnixNano(), int64(a.Window)
+	start := ns / window * window
+	if ns < start {
+		// integer division rounds towards zero, windows before the epoch start one window earlier
+		start -= window
+	}
+	return time.Unix(0, start).UTC()
+}
+
+// CheckRange returns an error when the time range from start to until, now when until is nil, covers more than
+// MaxAggregateWindows windows. A time range without start is bounded by the stored data points only, it is rejected
+// for windows narrower than MinOpenAggregateWindow.
+func (a Aggregation) CheckRange(start, until *time.Time, now time.Time) error {
+	if start == nil {
+		if a.Window < MinOpenAggregateWindow {
+			return fmt.Errorf("start is required for windows narrower than %s", MinOpenAggregateWindow)
+		}
+		return nil
+	}
+
+	end := now
+	if until != nil {
+		end = *until
+	}
+	if windows := int64(a.WindowStart(end).Sub(a.WindowStart(*start))/a.Window) + 1; windows > MaxAggregateWindows {
+		return fmt.Errorf("time range covers %d windows of %s, more than %d", windows, a.Window, MaxAggregateWindows)
+	}
+	return nil
+}
+
+// AggregatePoint is the aggregated value of the window starting at Time.
+type AggregatePoint struct {
+	Time  time.Time
+	Value float64
+}
@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"oc-data-be-challenge/internal/data/tagmap"
+	"strings"
+	"time"
+)
+
+// DataPointCursor is the position of a data point in query order, queries resume after it.
+// ReceivedAt breaks ties between data points with the same time, Source then TagSet break ties between the data points
+// of a batch, which share their received_at.
+type DataPointCursor struct {
+	Time       time.Time `json:"t"`
+	ReceivedAt time.Time `json:"r"`
+	Source     string    `json:"s,omitempty"`
+	// TagSet is the tag set of the data point, as returned by tagmap.Set.
+	TagSet string `json:"g,omitempty"`
+}
+
+// CursorOf returns the cursor positioned on point.
+func CursorOf(point DataPoint) DataPointCursor {
+	return DataPointCursor{Time: point.Time, ReceivedAt: point.ReceivedAt, Source: point.Source, TagSet: tagmap.Set(point.Tags)}
+}
+
+// Compare orders data points by time descending, then by received_at, source and tag set descending. This is the
+// order queries return data points in.
+func Compare(a, b DataPoint) int {
+	return CursorOf(a).Compare(CursorOf(b))
+}
+
+// Compare orders cursors like Compare orders the data points they are positioned on.
+func (c DataPointCursor) Compare(other DataPointCursor) int {
+	return cmp.Or(
+		other.Time.Compare(c.Time),
+		other.ReceivedAt.Compare(c.ReceivedAt),
+		strings.Compare(other.Source, c.Source),
+		strings.Compare(other.TagSet, c.TagSet),
+	)
+}
+
+// Encode returns the opaque string representation of the cursor.
+func (c DataPointCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeDataPointCursor parses a cursor returned by DataPointCursor.Encode.
+func DecodeDataPointCursor(s string) (DataPointCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return DataPointCursor{}, fmt.Errorf("failed to decode cursor: %w", err)
+	}
+
+	var c DataPointCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return DataPointCursor{}, fmt.Errorf("failed to decode cursor: %w", err)
+	}
+	if c.Time.IsZero() {
+		return DataPointCursor{}, errors.New("failed to decode cursor: missing time")
+	}
+	return c, nil
+}
@@ -4,7 +4,17 @@ import "time"
 
 type DataPoint struct {
 	Time       time.Time `json:"time,omitempty"`
-	Value      float32   `json:"value,omitempty"`
+	Value      float64   `json:"value,omitempty"`
 	Tags       []string  `json:"tags,omitempty"`
 	ReceivedAt time.Time `json:"received_at,omitempty"`
+	// Source is the name of the data source the data point was collected from.
+	Source string `json:"source,omitempty"`
+	// DiscardRule is the name of the discard rule that dropped the data point, empty for accepted data points.
+	DiscardRule string `json:"discard_rule,omitempty"`
+	// DiscardReason is the type of check that dropped the data point, e.g. "max_age" or "tag_deny".
+	DiscardReason string `json:"discard_reason,omitempty"`
+	// DiscardDetail is the tag or threshold that caused the data point to be dropped.
+	DiscardDetail string `json:"discard_detail,omitempty"`
+	// DiscardedBy is the collector instance that dropped the data point.
+	DiscardedBy string `json:"discarded_by,omitempty"`
 }
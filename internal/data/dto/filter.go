@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"slices"
+	"time"
+)
+
+// DataPointFilter restricts the data points returned by a query. The zero value matches every data point.
+type DataPointFilter struct {
+	// Start is the inclusive lower bound of the time range, nil for no lower bound.
+	Start *time.Time
+	// Until is the inclusive upper bound of the time range, nil for no upper bound.
+	Until *time.Time
+	// Tags are the tags a data point must all carry.
+	Tags []string
+	// TagsAny are the tags a data point must carry at least one of, ignored when empty.
+	TagsAny []string
+	// TagsNone are the tags a data point must not carry.
+	TagsNone []string
+	// Sources are the data sources a data point must come from one of, ignored when empty.
+	Sources []string
+	// After only keeps the data points following the cursor in query order, nil to start from the first one.
+	After *DataPointCursor
+	// Limit is the maximum number of data points returned, 0 for no limit.
+	Limit int
+}
+
+// Match reports whether point satisfies the filter. Limit is not taken into account.
+func (f DataPointFilter) Match(point DataPoint) bool {
+	if f.Start != nil && point.Time.Before(*f.Start) {
+		return false
+	}
+	if f.Until != nil && point.Time.After(*f.Until) {
+		return false
+	}
+	if f.After != nil && f.After.Compare(CursorOf(point)) >= 0 {
+		return false
+	}
+
+	if len(f.Sources) > 0 && !slices.Contains(f.Sources, point.Source) {
+		return false
+	}
+
+	for _, tag := range f.Tags {
+		if !slices.Contains(point.Tags, tag) {
+			return false
+		}
+	}
+
+	if len(f.TagsAny) > 0 && !slices.ContainsFunc(f.TagsAny, func(tag string) bool {
+		return slices.Contains(point.Tags, tag)
+	}) {
+		return false
+	}
+
+	for _, tag := range f.TagsNone {
+		if slices.Contains(point.Tags, tag) {
+			return false
+		}
+	}
+
+	return true
+}
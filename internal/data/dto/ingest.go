@@ -0,0 +1,13 @@
+package dto
+
+// IngestResult counts what became of a batch of data points collected or pushed at once.
+type IngestResult struct {
+	// Accepted is the number of data points stored as accepted.
+	Accepted int
+	// Discarded is the number of data points dropped by discard rules, they are stored as discarded.
+	Discarded int
+	// Duplicates is the number of data points already collected, they are skipped or stored as duplicates.
+	Duplicates int
+	// Invalid is the number of data points that failed to decode or to validate, they are skipped.
+	Invalid int
+}
@@ -0,0 +1,13 @@
+package dto
+
+import "time"
+
+// QueueStatus describes the collected data points waiting in the write-ahead log.
+type QueueStatus struct {
+	// Enabled tells whether collected data points go through the write-ahead log.
+	Enabled bool
+	// Depth is the number of data points waiting to be written to the storage backend.
+	Depth int
+	// OldestAppendedAt is the time at which the oldest waiting data point was appended, zero when none is waiting.
+	OldestAppendedAt time.Time
+}
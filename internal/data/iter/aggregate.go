@@ -0,0 +1,62 @@
+package iter
+
+import (
+	"fmt"
+	"oc-data-be-challenge/internal/data/dto"
+	"time"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// AggregatePointIter iterates over the windows returned by an InfluxDB aggregate query, the window start is read
+// from the window_start column and the aggregated value from the aggregate column.
+type AggregatePointIter struct {
+	iterator *influxdb3.QueryIterator
+}
+
+func NewAggregatePointIter(iterator *influxdb3.QueryIterator) *AggregatePointIter {
+	return &AggregatePointIter{iterator: iterator}
+}
+
+func (apIter *AggregatePointIter) Next() bool {
+	return apIter.iterator.Next()
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (apIter *AggregatePointIter) Err() error {
+	return apIter.iterator.Err()
+}
+
+func (apIter *AggregatePointIter) Value() (dto.AggregatePoint, error) {
+	t, err := parseWindowStart(apIter.iterator.Value()["window_start"])
+	if err != nil {
+		return dto.AggregatePoint{}, err
+	}
+
+	var value float64
+	switch v := apIter.iterator.Value()["aggregate"].(type) {
+	case float64:
+		value = v
+	case int64:
+		// count returns an integer
+		value = float64(v)
+	default:
+		return dto.AggregatePoint{}, fmt.Errorf("failed to parse aggregate from iterator %v", v)
+	}
+
+	return dto.AggregatePoint{Time: t, Value: value}, nil
+}
+
+// parseWindowStart parses the window_start column. Computed columns carry no InfluxDB metadata, so the client returns
+// them as an arrow timestamp rather than a time.Time.
+func parseWindowStart(v any) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case arrow.Timestamp:
+		return v.ToTime(arrow.Nanosecond), nil
+	default:
+		return time.Time{}, fmt.Errorf("failed to parse window start from iterator %v", v)
+	}
+}
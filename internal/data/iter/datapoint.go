@@ -3,11 +3,23 @@ package iter
 import (
 	"fmt"
 	"oc-data-be-challenge/internal/data/dto"
+	"oc-data-be-challenge/internal/data/tagmap"
+	"strings"
 	"time"
 
 	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
 )
 
+// DataPointIterator iterates over data points returned by a storage backend.
+type DataPointIterator interface {
+	// Next advances the iterator, it returns false when there are no more items.
+	Next() bool
+	// Value returns the data point at the current position.
+	Value() (dto.DataPoint, error)
+	// Err returns the error that stopped the iteration, if any.
+	Err() error
+}
+
 type DataPointIter struct {
 	iterator *influxdb3.QueryIterator
 }
@@ -20,6 +32,11 @@ func (dpIter *DataPointIter) Next() bool {
 	return dpIter.iterator.Next()
 }
 
+// Err returns the error that stopped the iteration, if any.
+func (dpIter *DataPointIter) Err() error {
+	return dpIter.iterator.Err()
+}
+
 func (dpIter *DataPointIter) Value() (dto.DataPoint, error) {
 	t, ok := dpIter.iterator.Value()["time"].(time.Time)
 	if !ok {
@@ -31,8 +48,59 @@ func (dpIter *DataPointIter) Value() (dto.DataPoint, error) {
 		return dto.DataPoint{}, fmt.Errorf("failed to parse value from iterator %v", dpIter.iterator.Value()["value"])
 	}
 
+	receivedAt, err := parseReceivedAt(dpIter.iterator.Value()["received_at"])
+	if err != nil {
+		return dto.DataPoint{}, err
+	}
+
+	// Tags are read from their tag columns, rows written before tags were mapped to columns carry them in the tags field.
+	tags := append(tagmap.Tags(dpIter.iterator.Value()), parseTags(dpIter.iterator.Value()["tags"])...)
+
+	// The source column is null for data points collected before sources were recorded.
+	source, _ := dpIter.iterator.Value()["source"].(string)
+
+	// Discard columns only exist in the discarded table and are null for points discarded before they were recorded.
+	discardRule, _ := dpIter.iterator.Value()["discard_rule"].(string)
+	discardReason, _ := dpIter.iterator.Value()["discard_reason"].(string)
+	discardDetail, _ := dpIter.iterator.Value()["discard_detail"].(string)
+	discardedBy, _ := dpIter.iterator.Value()["discarded_by"].(string)
+
 	return dto.DataPoint{
-		Time:  t,
-		Value: float32(val),
+		Time:          t,
+		Value:         val,
+		Tags:          tags,
+		ReceivedAt:    receivedAt,
+		Source:        source,
+		DiscardRule:   discardRule,
+		DiscardReason: discardReason,
+		DiscardDetail: discardDetail,
+		DiscardedBy:   discardedBy,
 	}, nil
 }
+
+// parseTags parses the legacy tags field, the InfluxDB client writes a []string field in its fmt representation, e.g. "[a b]".
+func parseTags(v any) []string {
+	s, ok := v.(string)
+	if !ok {
+		return nil
+	}
+	return strings.Fields(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+}
+
+// parseReceivedAt parses the received_at field, the InfluxDB client writes a time.Time field as an RFC3339 string.
+func parseReceivedAt(v any) (time.Time, error) {
+	switch v := v.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse received_at from iterator %v: %w", v, err)
+		}
+		return t, nil
+	default:
+		return time.Time{}, fmt.Errorf("failed to parse received_at from iterator %v", v)
+	}
+}
@@ -0,0 +1,32 @@
+package iter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseTags tests parsing the tags field as written by the InfluxDB client.
+func TestParseTags(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, parseTags("[a b]"))
+	assert.Empty(t, parseTags("[]"))
+	assert.Nil(t, parseTags(nil))
+}
+
+// TestParseReceivedAt tests parsing the received_at field as written by the InfluxDB client.
+func TestParseReceivedAt(t *testing.T) {
+	want := time.Date(2025, 1, 1, 0, 0, 0, 123456789, time.UTC)
+
+	got, err := parseReceivedAt(want.Format(time.RFC3339Nano))
+	require.NoError(t, err)
+	assert.True(t, want.Equal(got))
+
+	got, err = parseReceivedAt(nil)
+	require.NoError(t, err)
+	assert.True(t, got.IsZero())
+
+	_, err = parseReceivedAt("not a time")
+	assert.Error(t, err)
+}
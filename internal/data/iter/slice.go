@@ -0,0 +1,30 @@
+package iter
+
+import "oc-data-be-challenge/internal/data/dto"
+
+// SliceDataPointIter iterates over an in-memory slice of data points.
+type SliceDataPointIter struct {
+	points []dto.DataPoint
+	pos    int
+}
+
+func NewSliceDataPointIter(points []dto.DataPoint) *SliceDataPointIter {
+	return &SliceDataPointIter{points: points, pos: -1}
+}
+
+func (sIter *SliceDataPointIter) Next() bool {
+	if sIter.pos+1 >= len(sIter.points) {
+		return false
+	}
+	sIter.pos++
+	return true
+}
+
+func (sIter *SliceDataPointIter) Value() (dto.DataPoint, error) {
+	return sIter.points[sIter.pos], nil
+}
+
+// Err always returns nil, iterating over a slice cannot fail.
+func (sIter *SliceDataPointIter) Err() error {
+	return nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"math"
+	"oc-data-be-challenge/internal/data/dto"
+	"slices"
+)
+
+// aggregatePoints reduces points, ordered as returned by a query, to one value per window of aggregation.
+// The result is ordered by window start descending like the InfluxDB implementation.
+func aggregatePoints(points []dto.DataPoint, aggregation dto.Aggregation) []dto.AggregatePoint {
+	var result []dto.AggregatePoint
+	var window []float64
+	for i, point := range points {
+		window = append(window, point.Value)
+
+		start := aggregation.WindowStart(point.Time)
+		if i+1 < len(points) && aggregation.WindowStart(points[i+1].Time).Equal(start) {
+			continue
+		}
+
+		result = append(result, dto.AggregatePoint{Time: start, Value: aggregateValues(window, aggregation)})
+		window = window[:0]
+	}
+
+	return result
+}
+
+// aggregateValues reduces the values of a window, ordered by time descending, to a single value.
+func aggregateValues(values []float64, aggregation dto.Aggregation) float64 {
+	switch aggregation.Func {
+	case dto.AggregateMin:
+		return slices.Min(values)
+	case dto.AggregateMax:
+		return slices.Max(values)
+	case dto.AggregateSum:
+		return sum(values)
+	case dto.AggregateCount:
+		return float64(len(values))
+	case dto.AggregateFirst:
+		return values[len(values)-1]
+	case dto.AggregateLast:
+		return values[0]
+	case dto.AggregatePercentile:
+		return percentile(values, aggregation.Percentile)
+	default:
+		return sum(values) / float64(len(values))
+	}
+}
+
+func sum(values []float64) float64 {
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// percentile returns the p percentile of values, interpolating linearly between the closest ranks.
+func percentile(values []float64, p float64) float64 {
+	sorted := slices.Sorted(slices.Values(values))
+	rank := p * float64(len(sorted)-1)
+	lo, hi := int(math.Floor(rank)), int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
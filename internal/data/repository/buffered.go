@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"oc-data-be-challenge/internal/data/dto"
+	"oc-data-be-challenge/internal/data/iter"
+	"slices"
+	"sync"
+	"time"
+)
+
+var _ DataPointStore = (*BufferedDataPoint)(nil)
+
+// BufferOptions configures a BufferedDataPoint.
+type BufferOptions struct {
+	// MaxBatchSize is the number of buffered data points that triggers a flush, it is also the largest batch written
+	// to the wrapped store at once. Defaults to DefaultMaxBatchSize.
+	MaxBatchSize int
+	// FlushInterval is how often the buffer is flushed, it bounds how long a data point stays buffered while the
+	// wrapped store is healthy. Defaults to DefaultFlushInterval.
+	FlushInterval time.Duration
+	// MaxBufferedPoints bounds the memory used by the buffer, writes block once it is full until data points are
+	// flushed. Defaults to DefaultMaxBufferedPoints.
+	MaxBufferedPoints int
+	// WriteTimeout bounds every batch write to the wrapped store. Defaults to DefaultWriteTimeout.
+	WriteTimeout time.Duration
+}
+
+const (
+	DefaultMaxBatchSize      = 500
+	DefaultFlushInterval     = time.Second
+	DefaultMaxBufferedPoints = 10000
+	DefaultWriteTimeout      = 10 * time.Second
+)
+
+// bufferedPoint is a data point waiting to be written to table.
+type bufferedPoint struct {
+	point dto.DataPoint
+	table string
+}
+
+// BufferedDataPoint is a write-behind buffer in front of a DataPointStore. Writes return once the data points are
+// buffered, they are written to the wrapped store in batches when the buffer holds MaxBatchSize data points or every
+// FlushInterval, whichever comes first. A batch that fails to be written stays buffered and is retried on the next
+// flush, when the buffer is full writes block until space is freed, pushing back on the caller.
+//
+// Queries are served by the wrapped store, so buffered data points are not returned until they are flushed.
+type BufferedDataPoint struct {
+	store DataPointStore
+	opts  BufferOptions
+
+	// slots holds one token per buffered data point, sending blocks once MaxBufferedPoints are buffered.
+	slots chan struct{}
+
+	mu      sync.Mutex
+	pending []bufferedPoint
+	closed  bool
+
+	// flushMu serializes flushes, so the batch at the front of pending is only written by one goroutine.
+	flushMu   sync.Mutex
+	flushCh   chan struct{}
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+	closeOnce sync.Once
+	logger    *slog.Logger
+}
+
+// NewBufferedDataPoint wraps store with a write buffer and starts flushing it in the background.
+// Close must be called to stop flushing and write the remaining buffered data points.
+func NewBufferedDataPoint(store DataPointStore, opts BufferOptions) *BufferedDataPoint {
+	if opts.MaxBatchSize <= 0 {
+		opts.MaxBatchSize = DefaultMaxBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.MaxBufferedPoints <= 0 {
+		opts.MaxBufferedPoints = DefaultMaxBufferedPoints
+	}
+	opts.MaxBufferedPoints = max(opts.MaxBufferedPoints, opts.MaxBatchSize)
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = DefaultWriteTimeout
+	}
+
+	bdp := &BufferedDataPoint{
+		store:   store,
+		opts:    opts,
+		slots:   make(chan struct{}, opts.MaxBufferedPoints),
+		flushCh: make(chan struct{}, 1),
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+		logger:  slog.With("component", "BufferedDataPoint"),
+	}
+	go bdp.flushLoop()
+	return bdp
+}
+
+// buffer adds points to the buffer, waiting for space while it is full. The data points buffered before ctx is done
+// are kept.
+func (bdp *BufferedDataPoint) buffer(ctx context.Context, points []dto.DataPoint, table string) error {
+	for _, point := range points {
+		select {
+		case bdp.slots <- struct{}{}:
+		default:
+			bdp.logger.WarnContext(ctx, "Write buffer full, waiting for flush", "buffered", bdp.Len())
+			select {
+			case bdp.slots <- struct{}{}:
+			case <-ctx.Done():
+				return fmt.Errorf("failed to buffer datapoint: %w", ctx.Err())
+			}
+		}
+
+		bdp.mu.Lock()
+		if bdp.closed {
+			bdp.mu.Unlock()
+			<-bdp.slots
+			return errors.New("failed to buffer datapoint: buffer is closed")
+		}
+		bdp.pending = append(bdp.pending, bufferedPoint{point: point, table: table})
+		full := len(bdp.pending) >= bdp.opts.MaxBatchSize
+		bdp.mu.Unlock()
+
+		if full {
+			select {
+			case bdp.flushCh <- struct{}{}:
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+func (bdp *BufferedDataPoint) Write(ctx context.Context, points ...dto.DataPoint) error {
+	return bdp.buffer(ctx, points, tableDataPoint)
+}
+
+func (bdp *BufferedDataPoint) WriteDiscard(ctx context.Context, points ...dto.DataPoint) error {
+	return bdp.buffer(ctx, points, tableDataPointDiscarded)
+}
+
+func (bdp *BufferedDataPoint) WriteDuplicate(ctx context.Context, points ...dto.DataPoint) error {
+	return bdp.buffer(ctx, points, tableDataPointDuplicate)
+}
+
+func (bdp *BufferedDataPoint) Query(ctx context.Context, filter dto.DataPointFilter) (iter.DataPointIterator, error) {
+	return bdp.store.Query(ctx, filter)
+}
+
+func (bdp *BufferedDataPoint) QueryDiscarded(ctx context.Context, filter dto.DataPointFilter, reason string) (iter.DataPointIterator, error) {
+	return bdp.store.QueryDiscarded(ctx, filter, reason)
+}
+
+func (bdp *BufferedDataPoint) Aggregate(ctx context.Context, filter dto.DataPointFilter, aggregation dto.Aggregation) ([]dto.AggregatePoint, error) {
+	return bdp.store.Aggregate(ctx, filter, aggregation)
+}
+
+func (bdp *BufferedDataPoint) Ping(ctx context.Context) error {
+	return bdp.store.Ping(ctx)
+}
+
+// Len returns the number of buffered data points.
+func (bdp *BufferedDataPoint) Len() int {
+	bdp.mu.Lock()
+	defer bdp.mu.Unlock()
+	return len(bdp.pending)
+}
+
+// Flush writes all buffered data points to the wrapped store. It stops at the first batch that fails to be written,
+// the data points of that batch and the following ones stay buffered.
+func (bdp *BufferedDataPoint) Flush(ctx context.Context) error {
+	bdp.flushMu.Lock()
+	defer bdp.flushMu.Unlock()
+
+	for {
+		bdp.mu.Lock()
+		table, batch := bdp.nextBatch()
+		bdp.mu.Unlock()
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := bdp.writeBatch(ctx, table, batch); err != nil {
+			return err
+		}
+
+		bdp.mu.Lock()
+		bdp.pending = slices.Delete(bdp.pending, 0, len(batch))
+		bdp.mu.Unlock()
+		for range batch {
+			<-bdp.slots
+		}
+	}
+}
+
+// nextBatch returns the data points at the front of the buffer that go to the same table, at most MaxBatchSize.
+// Batches never mix tables so a failed write never leaves a batch partially written.
+func (bdp *BufferedDataPoint) nextBatch() (string, []dto.DataPoint) {
+	if len(bdp.pending) == 0 {
+		return "", nil
+	}
+
+	table := bdp.pending[0].table
+	var batch []dto.DataPoint
+	for _, bp := range bdp.pending {
+		if len(batch) == bdp.opts.MaxBatchSize || bp.table != table {
+			break
+		}
+		batch = append(batch, bp.point)
+	}
+	return table, batch
+}
+
+// writeBatch writes batch to table in the wrapped store.
+func (bdp *BufferedDataPoint) writeBatch(ctx context.Context, table string, batch []dto.DataPoint) error {
+	ctx, cancel := context.WithTimeout(ctx, bdp.opts.WriteTimeout)
+	defer cancel()
+
+	var err error
+	switch table {
+	case tableDataPointDiscarded:
+		err = bdp.store.WriteDiscard(ctx, batch...)
+	case tableDataPointDuplicate:
+		err = bdp.store.WriteDuplicate(ctx, batch...)
+	default:
+		err = bdp.store.Write(ctx, batch...)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to flush %d datapoints: %w", len(batch), err)
+	}
+	return nil
+}
+
+func (bdp *BufferedDataPoint) flushLoop() {
+	defer close(bdp.doneCh)
+
+	ticker := time.NewTicker(bdp.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-bdp.stopCh:
+			return
+		case <-ticker.C:
+		case <-bdp.flushCh:
+		}
+
+		if err := bdp.Flush(context.Background()); err != nil {
+			bdp.logger.Error("Failed to flush write buffer", "buffered", bdp.Len(), "error", err)
+		}
+	}
+}
+
+// Close stops accepting writes and flushes the buffered data points, retrying every FlushInterval until they are all
+// written or ctx is done. It does not close the wrapped store.
+func (bdp *BufferedDataPoint) Close(ctx context.Context) error {
+	bdp.closeOnce.Do(func() {
+		bdp.mu.Lock()
+		bdp.closed = true
+		bdp.mu.Unlock()
+
+		close(bdp.stopCh)
+		<-bdp.doneCh
+	})
+
+	for {
+		err := bdp.Flush(ctx)
+		if err == nil {
+			return nil
+		}
+		bdp.logger.Warn("Failed to flush write buffer on close, retrying", "buffered", bdp.Len(), "error", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to flush %d buffered datapoints: %w", bdp.Len(), errors.Join(err, ctx.Err()))
+		case <-time.After(bdp.opts.FlushInterval):
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"oc-data-be-challenge/internal/data/dto"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyStore is a MemoryDataPoint whose writes fail while failing is set and which records the batch sizes.
+type flakyStore struct {
+	*MemoryDataPoint
+
+	mu      sync.Mutex
+	failing bool
+	batches []int
+}
+
+func (fs *flakyStore) setFailing(failing bool) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.failing = failing
+}
+
+func (fs *flakyStore) Write(ctx context.Context, points ...dto.DataPoint) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.failing {
+		return errors.New("store unavailable")
+	}
+	fs.batches = append(fs.batches, len(points))
+	return fs.MemoryDataPoint.Write(ctx, points...)
+}
+
+func (fs *flakyStore) batchSizes() []int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return append([]int(nil), fs.batches...)
+}
+
+func countPoints(t *testing.T, store DataPointStore) int {
+	result, err := store.Query(context.Background(), dto.DataPointFilter{})
+	require.NoError(t, err)
+	return len(collect(t, result))
+}
+
+// TestBufferedDataPoint_FlushOnSize tests that a full batch is written at once without waiting for the interval.
+func TestBufferedDataPoint_FlushOnSize(t *testing.T) {
+	store := &flakyStore{MemoryDataPoint: NewMemoryDataPoint()}
+	buffered := NewBufferedDataPoint(store, BufferOptions{MaxBatchSize: 3, FlushInterval: time.Hour})
+	defer buffered.Close(context.Background())
+
+	ctx := context.Background()
+	for i := range 3 {
+		require.NoError(t, buffered.Write(ctx, dto.DataPoint{Time: time.Unix(int64(i), 0)}))
+	}
+
+	assert.Eventually(t, func() bool { return countPoints(t, store) == 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []int{3}, store.batchSizes())
+	assert.Equal(t, 0, buffered.Len())
+}
+
+// TestBufferedDataPoint_FlushOnInterval tests that data points are flushed after the interval even if the batch is not full.
+func TestBufferedDataPoint_FlushOnInterval(t *testing.T) {
+	store := &flakyStore{MemoryDataPoint: NewMemoryDataPoint()}
+	buffered := NewBufferedDataPoint(store, BufferOptions{MaxBatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	defer buffered.Close(context.Background())
+
+	require.NoError(t, buffered.Write(context.Background(), dto.DataPoint{Time: time.Unix(1, 0)}))
+	require.NoError(t, buffered.WriteDiscard(context.Background(), dto.DataPoint{Time: time.Unix(2, 0), DiscardReason: "max_age"}))
+
+	assert.Eventually(t, func() bool { return countPoints(t, store) == 1 }, time.Second, 5*time.Millisecond)
+	discarded, err := store.QueryDiscarded(context.Background(), dto.DataPointFilter{}, "")
+	require.NoError(t, err)
+	assert.Len(t, collect(t, discarded), 1)
+}
+
+// TestBufferedDataPoint_Backpressure tests that writes block while the buffer is full and the store is failing,
+// and that the buffered data points are written once the store recovers.
+func TestBufferedDataPoint_Backpressure(t *testing.T) {
+	store := &flakyStore{MemoryDataPoint: NewMemoryDataPoint(), failing: true}
+	buffered := NewBufferedDataPoint(store, BufferOptions{MaxBatchSize: 2, MaxBufferedPoints: 4, FlushInterval: 10 * time.Millisecond})
+	defer buffered.Close(context.Background())
+
+	for i := range 4 {
+		require.NoError(t, buffered.Write(context.Background(), dto.DataPoint{Time: time.Unix(int64(i), 0)}))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := buffered.Write(ctx, dto.DataPoint{Time: time.Unix(4, 0)})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 4, buffered.Len())
+
+	store.setFailing(false)
+	require.NoError(t, buffered.Write(context.Background(), dto.DataPoint{Time: time.Unix(5, 0)}))
+	assert.Eventually(t, func() bool { return countPoints(t, store) == 5 }, time.Second, 5*time.Millisecond)
+}
+
+// TestBufferedDataPoint_Close tests that Close flushes the buffered data points and rejects later writes.
+func TestBufferedDataPoint_Close(t *testing.T) {
+	store := &flakyStore{MemoryDataPoint: NewMemoryDataPoint()}
+	buffered := NewBufferedDataPoint(store, BufferOptions{MaxBatchSize: 2, FlushInterval: time.Hour})
+
+	for i := range 5 {
+		require.NoError(t, buffered.Write(context.Background(), dto.DataPoint{Time: time.Unix(int64(i), 0)}))
+	}
+
+	require.NoError(t, buffered.Close(context.Background()))
+	assert.Equal(t, 5, countPoints(t, store))
+	assert.Error(t, buffered.Write(context.Background(), dto.DataPoint{}))
+}
+
+// TestBufferedDataPoint_CloseTimeout tests that Close gives up when the store keeps failing.
+func TestBufferedDataPoint_CloseTimeout(t *testing.T) {
+	store := &flakyStore{MemoryDataPoint: NewMemoryDataPoint(), failing: true}
+	buffered := NewBufferedDataPoint(store, BufferOptions{FlushInterval: 10 * time.Millisecond})
+	require.NoError(t, buffered.Write(context.Background(), dto.DataPoint{Time: time.Unix(1, 0)}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := buffered.Close(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, buffered.Len())
+}
@@ -3,67 +3,367 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"oc-data-be-challenge/internal/data/dto"
 	"oc-data-be-challenge/internal/data/iter"
+	"oc-data-be-challenge/internal/data/tagmap"
+	"oc-data-be-challenge/internal/metrics"
+	"oc-data-be-challenge/internal/tracing"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+const (
+	// tableDataPoint is the table accepted data points are written to.
+	tableDataPoint = "datapoint"
+	// tableDataPointDiscarded is the table discarded data points are written to.
+	tableDataPointDiscarded = "datapoint_discarded"
+	// tableDataPointDuplicate is the table data points collected more than once are written to.
+	tableDataPointDuplicate = "datapoint_duplicate"
+	// sourceColumn is the tag column storing the data source of a data point.
+	sourceColumn = "source"
+)
+
+// DataPointStore is a storage backend for data points.
+type DataPointStore interface {
+	// Write stores accepted data points.
+	Write(ctx context.Context, points ...dto.DataPoint) error
+	// WriteDiscard stores data points that were dropped by the collector.
+	WriteDiscard(ctx context.Context, points ...dto.DataPoint) error
+	// WriteDuplicate stores data points that were collected more than once.
+	WriteDuplicate(ctx context.Context, points ...dto.DataPoint) error
+	// Query returns the accepted data points matching filter, ordered by time descending then received_at,
+	// source and tag set descending, see dto.Compare.
+	Query(ctx context.Context, filter dto.DataPointFilter) (iter.DataPointIterator, error)
+	// QueryDiscarded returns the discarded data points matching filter, in the same order as Query.
+	// An empty reason returns the data points discarded for any reason.
+	QueryDiscarded(ctx context.Context, filter dto.DataPointFilter, reason string) (iter.DataPointIterator, error)
+	// Aggregate reduces the accepted data points matching filter to one value per window of aggregation, ordered by
+	// window start descending. Windows without data points are omitted, the After and Limit fields of filter are ignored.
+	// It fails when the time range of filter is rejected by dto.Aggregation.CheckRange.
+	Aggregate(ctx context.Context, filter dto.DataPointFilter, aggregation dto.Aggregation) ([]dto.AggregatePoint, error)
+	// Ping checks that the storage backend is reachable.
+	Ping(ctx context.Context) error
+}
+
+var _ DataPointStore = (*DataPoint)(nil)
+
+// influxClient is the part of the InfluxDB 3 client used by DataPoint.
+type influxClient interface {
+	Query(ctx context.Context, query string, options ...influxdb3.QueryOption) (*influxdb3.QueryIterator, error)
+	QueryWithParameters(ctx context.Context, query string, parameters influxdb3.QueryParameters, options ...influxdb3.QueryOption) (*influxdb3.QueryIterator, error)
+	WritePoints(ctx context.Context, points []*influxdb3.Point, options ...influxdb3.WriteOption) error
+}
+
+// DataPoint is the InfluxDB 3 implementation of DataPointStore.
 type DataPoint struct {
-	client *influxdb3.Client
+	client influxClient
+	// tagColumns are the tags stored in a tag column of their own, see tagmap.
+	tagColumns map[string]bool
 }
 
-func NewDataPoint(client *influxdb3.Client) *DataPoint {
-	return &DataPoint{client: client}
+// NewDataPoint creates a DataPoint storing the tags in tagColumns in a tag column of their own, every tag is stored in
+// the tag set column.
+func NewDataPoint(client *influxdb3.Client, tagColumns []string) *DataPoint {
+	return newDataPoint(client, tagColumns)
 }
 
-func (dp *DataPoint) write(ctx context.Context, point dto.DataPoint, table string) error {
-	err := dp.client.WritePoints(ctx, []*influxdb3.Point{
-		influxdb3.NewPoint(table,
-			nil,
-			map[string]any{
-				"value":       point.Value,
-				"tags":        point.Tags,
-				"received_at": point.ReceivedAt,
-			},
-			point.Time,
-		),
-	})
+func newDataPoint(client influxClient, tagColumns []string) *DataPoint {
+	indexed := make(map[string]bool, len(tagColumns))
+	for _, tag := range tagColumns {
+		indexed[tag] = true
+	}
+	return &DataPoint{client: client, tagColumns: indexed}
+}
+
+// write stores points in table with a single request.
+func (dp *DataPoint) write(ctx context.Context, points []dto.DataPoint, table string) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	influxPoints := make([]*influxdb3.Point, 0, len(points))
+	for _, point := range points {
+		influxPoints = append(influxPoints, newInfluxPoint(table, point, dp.tagColumns))
+	}
 
+	defer metrics.ObserveSince(metrics.RepositoryDuration.WithLabelValues("write", table), time.Now())
+	ctx, span := tracing.Start(ctx, "DataPoint.write", spanAttributes(table, attribute.Int("datapoints", len(points)))...)
+	err := dp.client.WritePoints(ctx, influxPoints)
+	tracing.End(span, err)
 	if err != nil {
 		return errors.Join(errors.New("failed to write datapoint"), err)
 	}
 	return nil
 }
 
-func (dp *DataPoint) Write(ctx context.Context, point dto.DataPoint) error {
-	return dp.write(ctx, point, "datapoint")
+// newInfluxPoint returns the row of point in table. The source, the tag set and the tags of the point in tagColumns are
+// written to tag columns, InfluxDB identifies a row by its time and tag columns so that the data points sharing their
+// time but not their source or tags are kept apart.
+func newInfluxPoint(table string, point dto.DataPoint, tagColumns map[string]bool) *influxdb3.Point {
+	tags := tagmap.Columns(point.Tags, tagColumns)
+	for name, value := range map[string]string{
+		sourceColumn:     point.Source,
+		tagmap.SetColumn: tagmap.Set(point.Tags),
+	} {
+		if value == "" {
+			continue
+		}
+		if tags == nil {
+			tags = map[string]string{}
+		}
+		tags[name] = value
+	}
+
+	fields := map[string]any{
+		"value":       point.Value,
+		"received_at": point.ReceivedAt,
+	}
+	for name, value := range map[string]string{
+		"discard_rule":   point.DiscardRule,
+		"discard_reason": point.DiscardReason,
+		"discard_detail": point.DiscardDetail,
+		"discarded_by":   point.DiscardedBy,
+	} {
+		if value != "" {
+			fields[name] = value
+		}
+	}
+	return influxdb3.NewPoint(table, tags, fields, point.Time)
 }
 
-func (dp *DataPoint) WriteDiscard(ctx context.Context, point dto.DataPoint) error {
-	return dp.write(ctx, point, "datapoint_discarded")
+func (dp *DataPoint) Write(ctx context.Context, points ...dto.DataPoint) error {
+	return dp.write(ctx, points, tableDataPoint)
 }
 
-func (dp *DataPoint) Query(ctx context.Context, start, until *time.Time) (*iter.DataPointIter, error) {
-	query := `SELECT * FROM datapoint`
-	parameters := influxdb3.QueryParameters{}
-	if start != nil {
-		query += ` WHERE time >= $start`
-		parameters["start"] = start
+func (dp *DataPoint) WriteDiscard(ctx context.Context, points ...dto.DataPoint) error {
+	return dp.write(ctx, points, tableDataPointDiscarded)
+}
+
+func (dp *DataPoint) WriteDuplicate(ctx context.Context, points ...dto.DataPoint) error {
+	return dp.write(ctx, points, tableDataPointDuplicate)
+}
+
+func (dp *DataPoint) Query(ctx context.Context, filter dto.DataPointFilter) (iter.DataPointIterator, error) {
+	columns, err := dp.columns(ctx, tableDataPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions, parameters := filterConditions(filter, columns)
+	return dp.query(ctx, tableDataPoint, columns, conditions, parameters, filter.Limit)
+}
+
+func (dp *DataPoint) QueryDiscarded(ctx context.Context, filter dto.DataPointFilter, reason string) (iter.DataPointIterator, error) {
+	columns, err := dp.columns(ctx, tableDataPointDiscarded)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions, parameters := filterConditions(filter, columns)
+	if reason != "" {
+		conditions = append(conditions, `discard_reason = $reason`)
+		parameters["reason"] = reason
+	}
+
+	return dp.query(ctx, tableDataPointDiscarded, columns, conditions, parameters, filter.Limit)
+}
+
+func (dp *DataPoint) Aggregate(ctx context.Context, filter dto.DataPointFilter, aggregation dto.Aggregation) (_ []dto.AggregatePoint, err error) {
+	if err := aggregation.CheckRange(filter.Start, filter.Until, time.Now()); err != nil {
+		return nil, err
+	}
+
+	columns, err := dp.columns(ctx, tableDataPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.After, filter.Limit = nil, 0
+	conditions, parameters := filterConditions(filter, columns)
+
+	bin := fmt.Sprintf(`date_bin(INTERVAL '%d nanoseconds', time, TIMESTAMP '1970-01-01T00:00:00Z')`, aggregation.Window.Nanoseconds())
+	query := `SELECT ` + bin + ` AS window_start, ` + aggregateExpression(aggregation) + ` AS aggregate FROM ` + tableDataPoint
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	query += ` GROUP BY ` + bin + ` ORDER BY window_start DESC`
+
+	defer metrics.ObserveSince(metrics.RepositoryDuration.WithLabelValues("aggregate", tableDataPoint), time.Now())
+	ctx, span := tracing.Start(ctx, "DataPoint.Aggregate", spanAttributes(tableDataPoint, attribute.String("db.query.text", query))...)
+	defer func() { tracing.End(span, err) }()
+	resultIter, err := dp.client.QueryWithParameters(ctx, query, parameters)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to execute query"), err)
+	}
+
+	aggregateIter := iter.NewAggregatePointIter(resultIter)
+	var points []dto.AggregatePoint
+	for aggregateIter.Next() {
+		point, err := aggregateIter.Value()
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+	if err := aggregateIter.Err(); err != nil {
+		return nil, errors.Join(errors.New("failed to execute query"), err)
+	}
+
+	return points, nil
+}
+
+// Ping runs a query selecting a constant in the database, it fails when InfluxDB is unreachable or rejects the query.
+func (dp *DataPoint) Ping(ctx context.Context) error {
+	resultIter, err := dp.client.Query(ctx, `SELECT 1`)
+	if err != nil {
+		return errors.Join(errors.New("failed to ping InfluxDB"), err)
 	}
+	for resultIter.Next() {
+	}
+	if err := resultIter.Err(); err != nil {
+		return errors.Join(errors.New("failed to ping InfluxDB"), err)
+	}
+	return nil
+}
 
-	if until != nil {
-		query += ` AND time <= $until`
-		parameters["until"] = until
+// aggregateExpression returns the SQL aggregate expression of the value column for aggregation.
+// Percentiles are approximated by InfluxDB, they are exact for small windows.
+func aggregateExpression(aggregation dto.Aggregation) string {
+	switch aggregation.Func {
+	case dto.AggregateMin:
+		return `min(value)`
+	case dto.AggregateMax:
+		return `max(value)`
+	case dto.AggregateSum:
+		return `sum(value)`
+	case dto.AggregateCount:
+		return `count(value)`
+	case dto.AggregateFirst:
+		return `first_value(value ORDER BY time ASC)`
+	case dto.AggregateLast:
+		return `last_value(value ORDER BY time ASC)`
+	case dto.AggregatePercentile:
+		// 15 significant digits drop the rounding error of the percent to fraction conversion, e.g. p99.9
+		return `approx_percentile_cont(value, ` + strconv.FormatFloat(aggregation.Percentile, 'g', 15, 64) + `)`
+	default:
+		return `avg(value)`
 	}
+}
 
-	query += ` ORDER BY time DESC`
+// query selects at most limit rows of table with columns matching all conditions, ordered by time descending then
+// received_at, source and tag set descending like dto.Compare. A limit that is not positive selects all rows.
+func (dp *DataPoint) query(ctx context.Context, table string, columns map[string]bool, conditions []string, parameters influxdb3.QueryParameters, limit int) (iter.DataPointIterator, error) {
+	query := `SELECT * FROM ` + table
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
 
+	query += ` ORDER BY time DESC, to_timestamp(received_at) DESC, ` + stringColumn(columns, sourceColumn) + ` DESC, ` + stringColumn(columns, tagmap.SetColumn) + ` DESC`
+	if limit > 0 {
+		query += fmt.Sprintf(` LIMIT %d`, limit)
+	}
+
+	// rows are streamed by the returned iterator, the latency is the time until the first ones are received
+	defer metrics.ObserveSince(metrics.RepositoryDuration.WithLabelValues("query", table), time.Now())
+	ctx, span := tracing.Start(ctx, "DataPoint.query", spanAttributes(table, attribute.String("db.query.text", query))...)
 	resultIter, err := dp.client.QueryWithParameters(ctx, query, parameters)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, errors.Join(errors.New("failed to execute query"), err)
 	}
 
 	return iter.NewDataPointIter(resultIter), nil
 }
+
+// spanAttributes returns the attributes of the spans of the operations on table.
+func spanAttributes(table string, attrs ...attribute.KeyValue) []attribute.KeyValue {
+	return append([]attribute.KeyValue{attribute.String("db.system.name", "influxdb"), attribute.String("db.collection.name", table)}, attrs...)
+}
+
+// filterConditions returns the SQL conditions and parameters selecting the rows matching filter from a table with
+// columns. Source and tag filters never match when their column does not exist yet, rows rewritten by MigrateTags are
+// skipped.
+func filterConditions(filter dto.DataPointFilter, columns map[string]bool) ([]string, influxdb3.QueryParameters) {
+	var conditions []string
+	parameters := influxdb3.QueryParameters{}
+	if filter.Start != nil {
+		conditions = append(conditions, `time >= $start`)
+		parameters["start"] = filter.Start
+	}
+
+	if filter.Until != nil {
+		conditions = append(conditions, `time <= $until`)
+		parameters["until"] = filter.Until
+	}
+
+	if filter.After != nil {
+		// the rows following the cursor in query order, compared key by key from the last one: time, received_at,
+		// source then tag set. received_at is stored as an RFC 3339 string, it is converted to compare instants rather
+		// than strings.
+		after := stringColumn(columns, tagmap.SetColumn) + ` < $after_tag_set`
+		for _, key := range [][2]string{
+			{stringColumn(columns, sourceColumn), `$after_source`},
+			{`to_timestamp(received_at)`, `to_timestamp($after_received_at)`},
+			{`time`, `$after_time`},
+		} {
+			after = `(` + key[0] + ` < ` + key[1] + ` OR (` + key[0] + ` = ` + key[1] + ` AND ` + after + `))`
+		}
+		conditions = append(conditions, after)
+		parameters["after_time"] = filter.After.Time
+		parameters["after_received_at"] = filter.After.ReceivedAt.Format(time.RFC3339Nano)
+		parameters["after_source"] = filter.After.Source
+		parameters["after_tag_set"] = filter.After.TagSet
+	}
+
+	switch {
+	case len(filter.Sources) == 0:
+	case !columns[sourceColumn]:
+		// the source tag column is only created once a data point carries a source
+		conditions = append(conditions, `false`)
+	default:
+		sourceConditions := make([]string, 0, len(filter.Sources))
+		for i, source := range filter.Sources {
+			name := fmt.Sprintf("source_%d", i)
+			sourceConditions = append(sourceConditions, sourceColumn+` = $`+name)
+			parameters[name] = source
+		}
+		conditions = append(conditions, `(`+strings.Join(sourceConditions, ` OR `)+`)`)
+	}
+
+	for i, tag := range filter.Tags {
+		conditions = append(conditions, tagCondition(parameters, columns, fmt.Sprintf("tag_%d", i), tag))
+	}
+
+	if len(filter.TagsAny) > 0 {
+		anyConditions := make([]string, 0, len(filter.TagsAny))
+		for i, tag := range filter.TagsAny {
+			anyConditions = append(anyConditions, tagCondition(parameters, columns, fmt.Sprintf("tag_any_%d", i), tag))
+		}
+		conditions = append(conditions, `(`+strings.Join(anyConditions, ` OR `)+`)`)
+	}
+
+	for i, tag := range filter.TagsNone {
+		conditions = append(conditions, `NOT (`+tagCondition(parameters, columns, fmt.Sprintf("tag_none_%d", i), tag)+`)`)
+	}
+
+	if columns[legacyTagsColumn] {
+		conditions = append(conditions, `coalesce(tags, '') <> $tags_migrated`)
+		parameters["tags_migrated"] = legacyTagsMigrated
+	}
+
+	return conditions, parameters
+}
+
+// stringColumn returns the SQL expression of the string column name of a table with columns, empty for the rows without
+// it and for all rows when the table has no such column yet.
+func stringColumn(columns map[string]bool, name string) string {
+	if !columns[name] {
+		return `''`
+	}
+	return `coalesce(` + tagmap.Identifier(name) + `, '')`
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"oc-data-be-challenge/internal/data/dto"
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFilterConditions tests that the filter is turned into parameterised SQL conditions.
+func TestFilterConditions(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	filter := dto.DataPointFilter{
+		Start:    &start,
+		Tags:     []string{"a"},
+		TagsAny:  []string{"b", "c"},
+		TagsNone: []string{"d"},
+		Sources:  []string{"north", "south"},
+	}
+
+	conditions, parameters := filterConditions(filter, map[string]bool{"source": true, "tag_a": true, "tag_b": true, "tag_d": true})
+	assert.Equal(t, []string{
+		`time >= $start`,
+		`(source = $source_0 OR source = $source_1)`,
+		`"tag_a" IS NOT NULL`,
+		`("tag_b" IS NOT NULL OR false)`,
+		`NOT ("tag_d" IS NOT NULL)`,
+	}, conditions)
+	assert.Equal(t, influxdb3.QueryParameters{
+		"start":    &start,
+		"source_0": "north",
+		"source_1": "south",
+	}, parameters)
+}
+
+// TestFilterConditions_NoSourceColumn tests that a source filter matches no row of a table without source column.
+func TestFilterConditions_NoSourceColumn(t *testing.T) {
+	conditions, parameters := filterConditions(dto.DataPointFilter{Sources: []string{"north"}}, map[string]bool{})
+	assert.Equal(t, []string{`false`}, conditions)
+	assert.Empty(t, parameters)
+}
+
+// TestNewInfluxPoint tests that the data points of two sources sharing their time and tags are written to different
+// rows, InfluxDB identifying a row by its time and tag columns, and that only indexed tags get a column of their own.
+func TestNewInfluxPoint(t *testing.T) {
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	indexed := map[string]bool{"a": true}
+	north := newInfluxPoint(tableDataPoint, dto.DataPoint{Time: at, Value: 1, ReceivedAt: at, Source: "north", Tags: []string{"a", "b"}}, indexed)
+	south := newInfluxPoint(tableDataPoint, dto.DataPoint{Time: at, Value: 2, ReceivedAt: at, Source: "south", Tags: []string{"a", "b"}}, indexed)
+
+	assert.ElementsMatch(t, []string{"source", "tagset", "tag_a"}, north.GetTagNames())
+	assert.ElementsMatch(t, []string{"source", "tagset", "tag_a"}, south.GetTagNames())
+	assert.Nil(t, north.GetField("source"))
+	assert.Nil(t, north.GetField("tagset"))
+	for point, want := range map[*influxdb3.Point]string{north: "north", south: "south"} {
+		source, ok := point.GetTag("source")
+		assert.True(t, ok)
+		assert.Equal(t, want, source)
+		tagSet, ok := point.GetTag("tagset")
+		assert.True(t, ok)
+		assert.Equal(t, ",a,b,", tagSet)
+	}
+
+	// data points of a source sharing their time but not their unindexed tags are written to different rows too
+	other := newInfluxPoint(tableDataPoint, dto.DataPoint{Time: at, Value: 3, ReceivedAt: at, Source: "north", Tags: []string{"a", "c"}}, indexed)
+	tagSet, _ := other.GetTag("tagset")
+	assert.Equal(t, ",a,c,", tagSet)
+
+	unlabelled := newInfluxPoint(tableDataPoint, dto.DataPoint{Time: at, Value: 4, ReceivedAt: at}, indexed)
+	assert.Empty(t, unlabelled.GetTagNames())
+}
+
+// TestFilterConditions_TagSet tests that tags are searched in the tag set column, and on their column when the table
+// has one.
+func TestFilterConditions_TagSet(t *testing.T) {
+	conditions, parameters := filterConditions(dto.DataPointFilter{
+		Tags:     []string{"a"},
+		TagsNone: []string{"c,d"},
+	}, map[string]bool{"tagset": true, "tag_a": true})
+
+	assert.Equal(t, []string{
+		`("tag_a" IS NOT NULL OR strpos(coalesce("tagset", ''), $tag_0_set) > 0)`,
+		`NOT (strpos(coalesce("tagset", ''), $tag_none_0_set) > 0)`,
+	}, conditions)
+	assert.Equal(t, influxdb3.QueryParameters{
+		"tag_0_set":      ",a,",
+		"tag_none_0_set": ",c%2Cd,",
+	}, parameters)
+}
+
+// TestFilterConditions_LegacyTags tests that rows carrying their tags in the legacy tags field are matched too.
+func TestFilterConditions_LegacyTags(t *testing.T) {
+	tags := `strpos(concat(' ', btrim(coalesce(tags, ''), '[]'), ' '), `
+
+	conditions, parameters := filterConditions(dto.DataPointFilter{
+		Tags:     []string{"a"},
+		TagsAny:  []string{"b", "c"},
+		TagsNone: []string{"d"},
+	}, map[string]bool{"tags": true, "tag_a": true})
+
+	assert.Equal(t, []string{
+		`("tag_a" IS NOT NULL OR ` + tags + `$tag_0) > 0)`,
+		`(` + tags + `$tag_any_0) > 0 OR ` + tags + `$tag_any_1) > 0)`,
+		`NOT (` + tags + `$tag_none_0) > 0)`,
+		`coalesce(tags, '') <> $tags_migrated`,
+	}, conditions)
+	assert.Equal(t, influxdb3.QueryParameters{
+		"tag_0":         " a ",
+		"tag_any_0":     " b ",
+		"tag_any_1":     " c ",
+		"tag_none_0":    " d ",
+		"tags_migrated": legacyTagsMigrated,
+	}, parameters)
+}
+
+// TestFilterConditions_After tests that the rows following the cursor are selected key by key, the keys missing from
+// the table being empty.
+func TestFilterConditions_After(t *testing.T) {
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cursor := dto.DataPointCursor{Time: at, ReceivedAt: at, Source: "north", TagSet: ",a,"}
+
+	conditions, parameters := filterConditions(dto.DataPointFilter{After: &cursor}, map[string]bool{"source": true})
+	assert.Equal(t, []string{
+		`(time < $after_time OR (time = $after_time AND ` +
+			`(to_timestamp(received_at) < to_timestamp($after_received_at) OR (to_timestamp(received_at) = to_timestamp($after_received_at) AND ` +
+			`(coalesce("source", '') < $after_source OR (coalesce("source", '') = $after_source AND '' < $after_tag_set))))))`,
+	}, conditions)
+	assert.Equal(t, influxdb3.QueryParameters{
+		"after_time":        at,
+		"after_received_at": "2025-01-01T00:00:00Z",
+		"after_source":      "north",
+		"after_tag_set":     ",a,",
+	}, parameters)
+}
+
+// TestAggregateExpression tests the SQL expression of every aggregate function.
+func TestAggregateExpression(t *testing.T) {
+	tests := []struct {
+		fn   string
+		want string
+	}{
+		{fn: "mean", want: `avg(value)`},
+		{fn: "count", want: `count(value)`},
+		{fn: "first", want: `first_value(value ORDER BY time ASC)`},
+		{fn: "last", want: `last_value(value ORDER BY time ASC)`},
+		{fn: "p95", want: `approx_percentile_cont(value, 0.95)`},
+		{fn: "p99.9", want: `approx_percentile_cont(value, 0.999)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fn, func(t *testing.T) {
+			aggregation, err := dto.ParseAggregation(time.Minute, tt.fn)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, aggregateExpression(aggregation))
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"oc-data-be-challenge/internal/data/dto"
+	"oc-data-be-challenge/internal/data/iter"
+	"oc-data-be-challenge/internal/data/segment"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+var _ DataPointStore = (*EmbeddedDataPoint)(nil)
+
+// EmbeddedDataPoint is an on-disk implementation of DataPointStore that does not need any external service.
+// Every table is stored in its own segment store inside dir.
+type EmbeddedDataPoint struct {
+	tables map[string]*segment.Store
+}
+
+func NewEmbeddedDataPoint(dir string, opts segment.Options) (*EmbeddedDataPoint, error) {
+	edp := &EmbeddedDataPoint{tables: map[string]*segment.Store{}}
+	for _, table := range []string{tableDataPoint, tableDataPointDiscarded, tableDataPointDuplicate} {
+		store, err := segment.Open(filepath.Join(dir, table), opts)
+		if err != nil {
+			_ = edp.Close()
+			return nil, fmt.Errorf("failed to open table %s: %w", table, err)
+		}
+		edp.tables[table] = store
+	}
+
+	return edp, nil
+}
+
+// write appends points to table, none of them is stored when one fails to be written so that the batch can be
+// written again without duplicating the others.
+func (edp *EmbeddedDataPoint) write(points []dto.DataPoint, table string) error {
+	if err := edp.tables[table].Append(points...); err != nil {
+		return errors.Join(errors.New("failed to write datapoint"), err)
+	}
+	return nil
+}
+
+func (edp *EmbeddedDataPoint) Write(_ context.Context, points ...dto.DataPoint) error {
+	return edp.write(points, tableDataPoint)
+}
+
+func (edp *EmbeddedDataPoint) WriteDiscard(_ context.Context, points ...dto.DataPoint) error {
+	return edp.write(points, tableDataPointDiscarded)
+}
+
+func (edp *EmbeddedDataPoint) WriteDuplicate(_ context.Context, points ...dto.DataPoint) error {
+	return edp.write(points, tableDataPointDuplicate)
+}
+
+func (edp *EmbeddedDataPoint) Query(_ context.Context, filter dto.DataPointFilter) (iter.DataPointIterator, error) {
+	points, err := edp.query(tableDataPoint, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return iter.NewSliceDataPointIter(points), nil
+}
+
+func (edp *EmbeddedDataPoint) QueryDiscarded(_ context.Context, filter dto.DataPointFilter, reason string) (iter.DataPointIterator, error) {
+	points, err := edp.query(tableDataPointDiscarded, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return iter.NewSliceDataPointIter(filterByDiscardReason(points, reason)), nil
+}
+
+func (edp *EmbeddedDataPoint) Aggregate(_ context.Context, filter dto.DataPointFilter, aggregation dto.Aggregation) ([]dto.AggregatePoint, error) {
+	if err := aggregation.CheckRange(filter.Start, filter.Until, time.Now()); err != nil {
+		return nil, err
+	}
+
+	filter.After, filter.Limit = nil, 0
+	points, err := edp.query(tableDataPoint, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return aggregatePoints(points, aggregation), nil
+}
+
+// Ping always succeeds, the table stores are opened with the store and stay open until it is closed.
+func (edp *EmbeddedDataPoint) Ping(_ context.Context) error {
+	return nil
+}
+
+// query scans the table store from the most recent data point in the range of filter and applies the remaining
+// conditions in memory. Scanning stops once the limit is reached and every data point sharing the time of the last
+// kept one has been seen, so ties are ordered by received_at like the other stores.
+func (edp *EmbeddedDataPoint) query(table string, filter dto.DataPointFilter) ([]dto.DataPoint, error) {
+	until := filter.Until
+	if filter.After != nil && (until == nil || filter.After.Time.Before(*until)) {
+		until = &filter.After.Time
+	}
+
+	var points []dto.DataPoint
+	err := edp.tables[table].Scan(filter.Start, until, func(point dto.DataPoint) bool {
+		if filter.Limit > 0 && len(points) >= filter.Limit && !point.Time.Equal(points[len(points)-1].Time) {
+			return false
+		}
+		if filter.Match(point) {
+			points = append(points, point)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to execute query"), err)
+	}
+
+	slices.SortStableFunc(points, dto.Compare)
+	return limitPoints(points, filter.Limit), nil
+}
+
+// Close closes all table stores.
+func (edp *EmbeddedDataPoint) Close() error {
+	var errs []error
+	for _, store := range edp.tables {
+		errs = append(errs, store.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"oc-data-be-challenge/internal/data/dto"
+	"oc-data-be-challenge/internal/data/iter"
+	"slices"
+	"sync"
+	"time"
+)
+
+var _ DataPointStore = (*MemoryDataPoint)(nil)
+
+// MemoryDataPoint is an in-memory implementation of DataPointStore.
+// Data is lost when the process exits, it is meant for tests and ephemeral deployments.
+type MemoryDataPoint struct {
+	mu     sync.RWMutex
+	tables map[string][]dto.DataPoint
+}
+
+func NewMemoryDataPoint() *MemoryDataPoint {
+	return &MemoryDataPoint{tables: map[string][]dto.DataPoint{}}
+}
+
+func (mdp *MemoryDataPoint) write(points []dto.DataPoint, table string) error {
+	mdp.mu.Lock()
+	defer mdp.mu.Unlock()
+
+	mdp.tables[table] = append(mdp.tables[table], points...)
+	return nil
+}
+
+func (mdp *MemoryDataPoint) Write(_ context.Context, points ...dto.DataPoint) error {
+	return mdp.write(points, tableDataPoint)
+}
+
+func (mdp *MemoryDataPoint) WriteDiscard(_ context.Context, points ...dto.DataPoint) error {
+	return mdp.write(points, tableDataPointDiscarded)
+}
+
+func (mdp *MemoryDataPoint) WriteDuplicate(_ context.Context, points ...dto.DataPoint) error {
+	return mdp.write(points, tableDataPointDuplicate)
+}
+
+func (mdp *MemoryDataPoint) Query(_ context.Context, filter dto.DataPointFilter) (iter.DataPointIterator, error) {
+	mdp.mu.RLock()
+	defer mdp.mu.RUnlock()
+
+	return iter.NewSliceDataPointIter(filterPoints(mdp.tables[tableDataPoint], filter)), nil
+}
+
+func (mdp *MemoryDataPoint) QueryDiscarded(_ context.Context, filter dto.DataPointFilter, reason string) (iter.DataPointIterator, error) {
+	mdp.mu.RLock()
+	defer mdp.mu.RUnlock()
+
+	return iter.NewSliceDataPointIter(filterByDiscardReason(filterPoints(mdp.tables[tableDataPointDiscarded], filter), reason)), nil
+}
+
+func (mdp *MemoryDataPoint) Aggregate(_ context.Context, filter dto.DataPointFilter, aggregation dto.Aggregation) ([]dto.AggregatePoint, error) {
+	if err := aggregation.CheckRange(filter.Start, filter.Until, time.Now()); err != nil {
+		return nil, err
+	}
+
+	mdp.mu.RLock()
+	defer mdp.mu.RUnlock()
+
+	filter.After, filter.Limit = nil, 0
+	return aggregatePoints(filterPoints(mdp.tables[tableDataPoint], filter), aggregation), nil
+}
+
+// Ping always succeeds, data points are kept in memory.
+func (mdp *MemoryDataPoint) Ping(_ context.Context) error {
+	return nil
+}
+
+// filterByDiscardReason returns the points discarded for reason, all points when reason is empty.
+func filterByDiscardReason(points []dto.DataPoint, reason string) []dto.DataPoint {
+	if reason == "" {
+		return points
+	}
+	return slices.DeleteFunc(points, func(point dto.DataPoint) bool {
+		return point.DiscardReason != reason
+	})
+}
+
+// filterPoints returns a copy of the points matching filter, in query order and capped to the filter limit.
+func filterPoints(points []dto.DataPoint, filter dto.DataPointFilter) []dto.DataPoint {
+	result := make([]dto.DataPoint, 0, len(points))
+	for _, point := range points {
+		if filter.Match(point) {
+			result = append(result, point)
+		}
+	}
+
+	slices.SortStableFunc(result, dto.Compare)
+	return limitPoints(result, filter.Limit)
+}
+
+// limitPoints returns the first limit points, all points when limit is not positive.
+func limitPoints(points []dto.DataPoint, limit int) []dto.DataPoint {
+	if limit > 0 && len(points) > limit {
+		return points[:limit]
+	}
+	return points
+}
@@ -0,0 +1,433 @@
+package repository
+
+import (
+	"context"
+	"oc-data-be-challenge/internal/data/dto"
+	"oc-data-be-challenge/internal/data/iter"
+	"oc-data-be-challenge/internal/data/segment"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storeFactories returns the in-process DataPointStore implementations under test.
+func storeFactories(t *testing.T) map[string]func() DataPointStore {
+	return map[string]func() DataPointStore{
+		"memory": func() DataPointStore {
+			return NewMemoryDataPoint()
+		},
+		"embedded": func() DataPointStore {
+			store, err := NewEmbeddedDataPoint(t.TempDir(), segment.Options{})
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = store.Close() })
+			return store
+		},
+	}
+}
+
+func collect(t *testing.T, it iter.DataPointIterator) []dto.DataPoint {
+	var points []dto.DataPoint
+	for it.Next() {
+		point, err := it.Value()
+		require.NoError(t, err)
+		points = append(points, point)
+	}
+	return points
+}
+
+// TestDataPointStore_QueryOrderAndRange tests that Query honours the time range and orders by time descending.
+func TestDataPointStore_QueryOrderAndRange(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			ctx := context.Background()
+
+			for i := range 5 {
+				require.NoError(t, store.Write(ctx, dto.DataPoint{
+					Time:       base.Add(time.Duration(i) * time.Minute),
+					Value:      float64(i),
+					Tags:       []string{"tag"},
+					ReceivedAt: base,
+				}))
+			}
+
+			all, err := store.Query(ctx, dto.DataPointFilter{})
+			require.NoError(t, err)
+			points := collect(t, all)
+			require.Len(t, points, 5)
+			for i := 1; i < len(points); i++ {
+				assert.True(t, points[i-1].Time.After(points[i].Time), "points should be ordered by time descending")
+			}
+
+			start := base.Add(1 * time.Minute)
+			until := base.Add(3 * time.Minute)
+			ranged, err := store.Query(ctx, dto.DataPointFilter{Start: &start, Until: &until})
+			require.NoError(t, err)
+			points = collect(t, ranged)
+			require.Len(t, points, 3)
+			assert.Equal(t, float64(3), points[0].Value)
+			assert.Equal(t, float64(1), points[2].Value)
+		})
+	}
+}
+
+// TestDataPointStore_WriteDiscardNotQueried tests that discarded points are not returned by Query.
+func TestDataPointStore_WriteDiscardNotQueried(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			ctx := context.Background()
+
+			require.NoError(t, store.WriteDiscard(ctx, dto.DataPoint{Time: time.Now(), Value: 1}))
+
+			result, err := store.Query(ctx, dto.DataPointFilter{})
+			require.NoError(t, err)
+			assert.Empty(t, collect(t, result))
+		})
+	}
+}
+
+// TestDataPointStore_QueryDiscardedByReason tests that discarded points are filtered by reason and keep their discard details.
+func TestDataPointStore_QueryDiscardedByReason(t *testing.T) {
+	now := time.Now().UTC()
+
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			ctx := context.Background()
+
+			require.NoError(t, store.WriteDiscard(ctx, dto.DataPoint{
+				Time: now.Add(-2 * time.Hour), Value: 1,
+				DiscardRule: "max_age", DiscardReason: "max_age", DiscardDetail: "1h0m0s", DiscardedBy: "collector-1",
+			}))
+			require.NoError(t, store.WriteDiscard(ctx, dto.DataPoint{
+				Time: now, Value: 2, Tags: []string{"system"},
+				DiscardRule: "denied_tags", DiscardReason: "tag_deny", DiscardDetail: "system", DiscardedBy: "collector-1",
+			}))
+
+			all, err := store.QueryDiscarded(ctx, dto.DataPointFilter{}, "")
+			require.NoError(t, err)
+			assert.Len(t, collect(t, all), 2)
+
+			start := now.Add(-time.Hour)
+			ranged, err := store.QueryDiscarded(ctx, dto.DataPointFilter{Start: &start}, "")
+			require.NoError(t, err)
+			assert.Len(t, collect(t, ranged), 1)
+
+			byReason, err := store.QueryDiscarded(ctx, dto.DataPointFilter{}, "tag_deny")
+			require.NoError(t, err)
+			points := collect(t, byReason)
+			require.Len(t, points, 1)
+			assert.Equal(t, "denied_tags", points[0].DiscardRule)
+			assert.Equal(t, "system", points[0].DiscardDetail)
+			assert.Equal(t, "collector-1", points[0].DiscardedBy)
+			assert.Equal(t, []string{"system"}, points[0].Tags)
+		})
+	}
+}
+
+// TestDataPointStore_QueryTags tests that Query honours the tag, tag_any and tag_none filters.
+func TestDataPointStore_QueryTags(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tagSets := [][]string{{"temp", "north"}, {"temp", "south"}, {"humidity", "north"}, nil}
+
+	tests := []struct {
+		name   string
+		filter dto.DataPointFilter
+		want   []float64
+	}{
+		{name: "no filter", want: []float64{3, 2, 1, 0}},
+		{name: "tag", filter: dto.DataPointFilter{Tags: []string{"temp"}}, want: []float64{1, 0}},
+		{name: "all tags", filter: dto.DataPointFilter{Tags: []string{"temp", "north"}}, want: []float64{0}},
+		{name: "tag any", filter: dto.DataPointFilter{TagsAny: []string{"south", "humidity"}}, want: []float64{2, 1}},
+		{name: "tag none", filter: dto.DataPointFilter{TagsNone: []string{"north"}}, want: []float64{3, 1}},
+		{name: "partial tag name", filter: dto.DataPointFilter{Tags: []string{"nor"}}},
+		{
+			name:   "combined",
+			filter: dto.DataPointFilter{Tags: []string{"north"}, TagsAny: []string{"temp", "humidity"}, TagsNone: []string{"humidity"}},
+			want:   []float64{0},
+		},
+	}
+
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			ctx := context.Background()
+			for i, tags := range tagSets {
+				require.NoError(t, store.Write(ctx, dto.DataPoint{
+					Time: base.Add(time.Duration(i) * time.Minute), Value: float64(i), Tags: tags,
+				}))
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					result, err := store.Query(ctx, tt.filter)
+					require.NoError(t, err)
+
+					var values []float64
+					for _, point := range collect(t, result) {
+						values = append(values, point.Value)
+					}
+					assert.Equal(t, tt.want, values)
+				})
+			}
+		})
+	}
+}
+
+// TestDataPointStore_QuerySources tests that Query only returns data points from the requested sources and keeps
+// their source.
+func TestDataPointStore_QuerySources(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			ctx := context.Background()
+			for i, source := range []string{"north", "south", "east", "north"} {
+				require.NoError(t, store.Write(ctx, dto.DataPoint{
+					Time: base.Add(time.Duration(i) * time.Minute), Value: float64(i), Source: source,
+				}))
+			}
+
+			result, err := store.Query(ctx, dto.DataPointFilter{Sources: []string{"north", "east"}})
+			require.NoError(t, err)
+			points := collect(t, result)
+			require.Len(t, points, 3)
+			for i, want := range []string{"north", "east", "north"} {
+				assert.Equal(t, want, points[i].Source)
+			}
+		})
+	}
+}
+
+// TestDataPointStore_SameTimeAndTagsFromTwoSources tests that the data points of two sources sharing their time and
+// tags are both kept.
+func TestDataPointStore_SameTimeAndTagsFromTwoSources(t *testing.T) {
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			ctx := context.Background()
+			require.NoError(t, store.Write(ctx,
+				dto.DataPoint{Time: at, Value: 1, Tags: []string{"a"}, ReceivedAt: at, Source: "north"},
+				dto.DataPoint{Time: at, Value: 2, Tags: []string{"a"}, ReceivedAt: at, Source: "south"},
+			))
+
+			result, err := store.Query(ctx, dto.DataPointFilter{})
+			require.NoError(t, err)
+			points := collect(t, result)
+			require.Len(t, points, 2)
+			assert.Equal(t, "south", points[0].Source)
+			assert.Equal(t, 2.0, points[0].Value)
+			assert.Equal(t, "north", points[1].Source)
+			assert.Equal(t, 1.0, points[1].Value)
+
+			result, err = store.Query(ctx, dto.DataPointFilter{Sources: []string{"north"}})
+			require.NoError(t, err)
+			points = collect(t, result)
+			require.Len(t, points, 1)
+			assert.Equal(t, 1.0, points[0].Value)
+		})
+	}
+}
+
+// TestDataPointStore_QueryPagination tests that pages follow each other without gaps or duplicates,
+// including data points sharing the same time.
+func TestDataPointStore_QueryPagination(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			ctx := context.Background()
+
+			// three data points per minute, received in an order that differs from the write order
+			var want []dto.DataPoint
+			for i := range 9 {
+				point := dto.DataPoint{
+					Time:       base.Add(time.Duration(i/3) * time.Minute),
+					Value:      float64(i),
+					ReceivedAt: base.Add(time.Duration((i*2)%3) * time.Second),
+				}
+				require.NoError(t, store.Write(ctx, point))
+				want = append(want, point)
+			}
+			slices.SortFunc(want, dto.Compare)
+
+			var got []dto.DataPoint
+			filter := dto.DataPointFilter{Limit: 2}
+			for range len(want) {
+				result, err := store.Query(ctx, filter)
+				require.NoError(t, err)
+				page := collect(t, result)
+				if len(page) == 0 {
+					break
+				}
+				require.LessOrEqual(t, len(page), 2)
+
+				got = append(got, page...)
+				cursor := dto.CursorOf(page[len(page)-1])
+				filter.After = &cursor
+			}
+
+			require.Len(t, got, len(want))
+			for i := range want {
+				assert.Equal(t, want[i].Value, got[i].Value)
+			}
+		})
+	}
+}
+
+// TestDataPointStore_QueryPaginationWithinBatch tests that a page boundary falling between data points collected in the
+// same batch, which share their time and received_at, neither skips nor repeats any of them.
+func TestDataPointStore_QueryPaginationWithinBatch(t *testing.T) {
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			ctx := context.Background()
+
+			batch := []dto.DataPoint{
+				{Time: at, Value: 1, ReceivedAt: at, Source: "north", Tags: []string{"b"}},
+				{Time: at, Value: 2, ReceivedAt: at, Source: "north", Tags: []string{"a"}},
+				{Time: at, Value: 3, ReceivedAt: at, Source: "north"},
+				{Time: at, Value: 4, ReceivedAt: at, Source: "south", Tags: []string{"a", "b"}},
+				{Time: at, Value: 5, ReceivedAt: at, Source: "south", Tags: []string{"a"}},
+			}
+			require.NoError(t, store.Write(ctx, batch...))
+
+			var got []float64
+			filter := dto.DataPointFilter{Limit: 2}
+			for range len(batch) {
+				result, err := store.Query(ctx, filter)
+				require.NoError(t, err)
+				page := collect(t, result)
+				if len(page) == 0 {
+					break
+				}
+				require.LessOrEqual(t, len(page), 2)
+
+				for _, point := range page {
+					got = append(got, point.Value)
+				}
+				cursor := dto.CursorOf(page[len(page)-1])
+				filter.After = &cursor
+			}
+
+			// source then tag set descending
+			assert.Equal(t, []float64{4, 5, 1, 2, 3}, got)
+		})
+	}
+}
+
+// TestDataPointStore_Aggregate tests that data points are reduced to one value per window.
+func TestDataPointStore_Aggregate(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	// two windows of one minute: 1, 2, 3, 4 then 10, 20
+	offsets := []time.Duration{0, 15 * time.Second, 30 * time.Second, 45 * time.Second, time.Minute, 90 * time.Second}
+	values := []float64{1, 2, 3, 4, 10, 20}
+
+	tests := []struct {
+		fn   string
+		want []float64
+	}{
+		{fn: "mean", want: []float64{15, 2.5}},
+		{fn: "min", want: []float64{10, 1}},
+		{fn: "max", want: []float64{20, 4}},
+		{fn: "sum", want: []float64{30, 10}},
+		{fn: "count", want: []float64{2, 4}},
+		{fn: "first", want: []float64{10, 1}},
+		{fn: "last", want: []float64{20, 4}},
+		{fn: "p50", want: []float64{15, 2.5}},
+		{fn: "p100", want: []float64{20, 4}},
+	}
+
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			ctx := context.Background()
+			for i, offset := range offsets {
+				require.NoError(t, store.Write(ctx, dto.DataPoint{Time: base.Add(offset), Value: values[i]}))
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.fn, func(t *testing.T) {
+					aggregation, err := dto.ParseAggregation(time.Minute, tt.fn)
+					require.NoError(t, err)
+
+					points, err := store.Aggregate(ctx, dto.DataPointFilter{}, aggregation)
+					require.NoError(t, err)
+					require.Len(t, points, 2)
+					assert.True(t, base.Add(time.Minute).Equal(points[0].Time))
+					assert.True(t, base.Equal(points[1].Time))
+					assert.Equal(t, tt.want, []float64{points[0].Value, points[1].Value})
+				})
+			}
+
+			start, until := base.Add(time.Minute), base.Add(time.Hour)
+			aggregation, err := dto.ParseAggregation(time.Hour, "count")
+			require.NoError(t, err)
+			points, err := store.Aggregate(ctx, dto.DataPointFilter{Start: &start, Until: &until}, aggregation)
+			require.NoError(t, err)
+			assert.Equal(t, []dto.AggregatePoint{{Time: base, Value: 2}}, points)
+		})
+	}
+}
+
+// TestDataPointStore_AggregateRange tests that time ranges covering too many windows are rejected.
+func TestDataPointStore_AggregateRange(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			ctx := context.Background()
+
+			aggregation, err := dto.ParseAggregation(time.Second, "count")
+			require.NoError(t, err)
+
+			_, err = store.Aggregate(ctx, dto.DataPointFilter{}, aggregation)
+			assert.ErrorContains(t, err, "start is required")
+
+			until := start.Add(dto.MaxAggregateWindows * time.Second)
+			_, err = store.Aggregate(ctx, dto.DataPointFilter{Start: &start, Until: &until}, aggregation)
+			assert.ErrorContains(t, err, "more than")
+
+			until = until.Add(-time.Second)
+			_, err = store.Aggregate(ctx, dto.DataPointFilter{Start: &start, Until: &until}, aggregation)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+// TestEmbeddedDataPoint_Reopen tests that the embedded store keeps data points across restarts.
+func TestEmbeddedDataPoint_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	store, err := NewEmbeddedDataPoint(dir, segment.Options{})
+	require.NoError(t, err)
+	require.NoError(t, store.Write(ctx, dto.DataPoint{Time: now, Value: 42.5, Tags: []string{"a", "b"}}))
+	require.NoError(t, store.Close())
+
+	store, err = NewEmbeddedDataPoint(dir, segment.Options{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	result, err := store.Query(ctx, dto.DataPointFilter{})
+	require.NoError(t, err)
+	points := collect(t, result)
+	require.Len(t, points, 1)
+	assert.True(t, now.Equal(points[0].Time))
+	assert.Equal(t, float64(42.5), points[0].Value)
+	assert.Equal(t, []string{"a", "b"}, points[0].Tags)
+}
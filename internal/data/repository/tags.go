@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"oc-data-be-challenge/internal/data/dto"
+	"oc-data-be-challenge/internal/data/iter"
+	"oc-data-be-challenge/internal/data/tagmap"
+	"strings"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+)
+
+const (
+	// legacyTagsColumn is the field tags were stored in before they were mapped to tag columns, e.g. "[a b]".
+	legacyTagsColumn = "tags"
+	// legacyTagsMigrated replaces the legacy tags field of the rows rewritten by MigrateTags, queries skip these rows.
+	legacyTagsMigrated = "migrated"
+	// migrateBatchSize is the number of rows rewritten with a single write request by MigrateTags.
+	migrateBatchSize = 1000
+)
+
+// columns returns the names of the columns of table, tag columns are only created once a data point carries the tag.
+func (dp *DataPoint) columns(ctx context.Context, table string) (map[string]bool, error) {
+	resultIter, err := dp.client.QueryWithParameters(ctx,
+		`SELECT column_name FROM information_schema.columns WHERE table_schema = 'iox' AND table_name = $table`,
+		influxdb3.QueryParameters{"table": table},
+	)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to query table columns"), err)
+	}
+
+	columns := map[string]bool{}
+	for resultIter.Next() {
+		if name, ok := resultIter.Value()["column_name"].(string); ok {
+			columns[name] = true
+		}
+	}
+	if err := resultIter.Err(); err != nil {
+		return nil, errors.Join(errors.New("failed to query table columns"), err)
+	}
+
+	return columns, nil
+}
+
+// tagCondition binds tag to the parameter name and returns the SQL condition matching the rows carrying it, given
+// the columns of the table. Every row carries its tags in the tag set column, which is searched along with the tag
+// column of tag: the rows written before the tag was added to the tag columns do not have it set. Rows written before
+// tags were mapped to tag columns are matched on the legacy tags field.
+func tagCondition(parameters influxdb3.QueryParameters, columns map[string]bool, name string, tag string) string {
+	var matches []string
+	if column := tagmap.Column(tag); columns[column] {
+		matches = append(matches, tagmap.Identifier(column)+` IS NOT NULL`)
+	}
+	if columns[tagmap.SetColumn] {
+		parameters[name+"_set"] = tagmap.Member(tag)
+		matches = append(matches, `strpos(`+stringColumn(columns, tagmap.SetColumn)+`, $`+name+`_set) > 0`)
+	}
+	if columns[legacyTagsColumn] {
+		matches = append(matches, legacyTagCondition(parameters, name, tag)+` > 0`)
+	}
+
+	switch len(matches) {
+	case 0:
+		return `false`
+	case 1:
+		return matches[0]
+	default:
+		return `(` + strings.Join(matches, ` OR `) + `)`
+	}
+}
+
+// legacyTagCondition binds tag to the parameter name and returns the SQL expression locating it in the legacy tags
+// field. Tags are stored space separated within brackets, e.g. "[a b]", so the column is padded with spaces
+// and searched for " tag " to only match whole tags.
+func legacyTagCondition(parameters influxdb3.QueryParameters, name string, tag string) string {
+	parameters[name] = " " + tag + " "
+	return `strpos(concat(' ', btrim(coalesce(tags, ''), '[]'), ' '), $` + name + `)`
+}
+
+// MigrateTags rewrites the rows that carry their tags in the legacy tags field to tag columns and returns the number
+// of rewritten rows. InfluxDB cannot delete rows, the legacy rows are kept with their tags field set to
+// legacyTagsMigrated so that queries skip them. Rows without tags read the same in both layouts and are left
+// untouched. It can be interrupted and run again, only the rows that were not rewritten yet are rewritten.
+func (dp *DataPoint) MigrateTags(ctx context.Context) (int, error) {
+	migrated := 0
+	for _, table := range []string{tableDataPoint, tableDataPointDiscarded} {
+		n, err := dp.migrateTags(ctx, table)
+		migrated += n
+		if err != nil {
+			return migrated, err
+		}
+	}
+	return migrated, nil
+}
+
+// migrateTags rewrites the legacy rows of table in batches of migrateBatchSize.
+func (dp *DataPoint) migrateTags(ctx context.Context, table string) (int, error) {
+	columns, err := dp.columns(ctx, table)
+	if err != nil {
+		return 0, err
+	}
+	if !columns[legacyTagsColumn] {
+		return 0, nil
+	}
+
+	resultIter, err := dp.client.QueryWithParameters(ctx,
+		`SELECT * FROM `+table+` WHERE tags <> '[]' AND tags <> $tags_migrated`,
+		influxdb3.QueryParameters{"tags_migrated": legacyTagsMigrated},
+	)
+	if err != nil {
+		return 0, errors.Join(errors.New("failed to execute query"), err)
+	}
+
+	migrated := 0
+	points := iter.NewDataPointIter(resultIter)
+	batch := make([]dto.DataPoint, 0, migrateBatchSize)
+	for points.Next() {
+		point, err := points.Value()
+		if err != nil {
+			return migrated, err
+		}
+
+		batch = append(batch, point)
+		if len(batch) < migrateBatchSize {
+			continue
+		}
+		if err := dp.rewriteTags(ctx, table, batch); err != nil {
+			return migrated, err
+		}
+		migrated += len(batch)
+		batch = batch[:0]
+	}
+	if err := resultIter.Err(); err != nil {
+		return migrated, errors.Join(errors.New("failed to execute query"), err)
+	}
+
+	if err := dp.rewriteTags(ctx, table, batch); err != nil {
+		return migrated, err
+	}
+	return migrated + len(batch), nil
+}
+
+// rewriteTags writes points to table with tag columns, then marks the legacy rows they were read from as migrated.
+// The legacy rows are only marked once their rewrite is stored, an interrupted migration never loses a row.
+func (dp *DataPoint) rewriteTags(ctx context.Context, table string, points []dto.DataPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	if err := dp.write(ctx, points, table); err != nil {
+		return err
+	}
+
+	legacyPoints := make([]*influxdb3.Point, 0, len(points))
+	for _, point := range points {
+		legacyPoints = append(legacyPoints, influxdb3.NewPoint(table, nil, map[string]any{legacyTagsColumn: legacyTagsMigrated}, point.Time))
+	}
+	if err := dp.client.WritePoints(ctx, legacyPoints); err != nil {
+		return errors.Join(errors.New("failed to mark migrated datapoint"), err)
+	}
+	return nil
+}
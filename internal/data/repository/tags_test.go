@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"oc-data-be-challenge/internal/data/dto"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRow is a row of fakeInflux, identified by its time and tag columns.
+type fakeRow struct {
+	time   time.Time
+	tags   map[string]string
+	fields map[string]any
+}
+
+// fakeInflux is an in-memory InfluxDB answering the queries of MigrateTags. Like InfluxDB, a write to the time and tag
+// columns of an existing row updates its fields.
+type fakeInflux struct {
+	tables map[string][]*fakeRow
+	// calls logs the queries and writes, e.g. "columns datapoint", "select datapoint" and "write datapoint 2".
+	calls []string
+	// failWrite fails the write with this 1-based index, the writes are counted in writes.
+	failWrite int
+	writes    int
+}
+
+func (f *fakeInflux) Query(context.Context, string, ...influxdb3.QueryOption) (*influxdb3.QueryIterator, error) {
+	return nil, errors.New("unsupported query")
+}
+
+func (f *fakeInflux) QueryWithParameters(_ context.Context, query string, parameters influxdb3.QueryParameters, _ ...influxdb3.QueryOption) (*influxdb3.QueryIterator, error) {
+	if strings.Contains(query, "information_schema.columns") {
+		table := parameters["table"].(string)
+		f.calls = append(f.calls, "columns "+table)
+		var rows []map[string]any
+		for _, column := range f.columns(table) {
+			rows = append(rows, map[string]any{"column_name": column})
+		}
+		return queryIterator([]string{"column_name"}, rows)
+	}
+
+	table, predicate, ok := strings.Cut(strings.TrimPrefix(query, "SELECT * FROM "), " WHERE ")
+	if !ok || predicate != `tags <> '[]' AND tags <> $tags_migrated` {
+		return nil, fmt.Errorf("unsupported query %q", query)
+	}
+	f.calls = append(f.calls, "select "+table)
+	var rows []map[string]any
+	for _, row := range f.tables[table] {
+		if tags, ok := row.fields["tags"].(string); ok && tags != "[]" && tags != parameters["tags_migrated"] {
+			rows = append(rows, row.values())
+		}
+	}
+	return queryIterator(f.columns(table), rows)
+}
+
+func (f *fakeInflux) WritePoints(_ context.Context, points []*influxdb3.Point, _ ...influxdb3.WriteOption) error {
+	f.writes++
+	if f.writes == f.failWrite {
+		return errors.New("connection reset")
+	}
+
+	f.calls = append(f.calls, fmt.Sprintf("write %s %d", points[0].Values.MeasurementName, len(points)))
+	for _, point := range points {
+		table := point.Values.MeasurementName
+		fields := map[string]any{}
+		for name, value := range point.Values.Fields {
+			// the client writes time fields as RFC 3339 strings
+			if t, ok := value.(time.Time); ok {
+				value = t.Format(time.RFC3339Nano)
+			}
+			fields[name] = value
+		}
+
+		i := slices.IndexFunc(f.tables[table], func(row *fakeRow) bool {
+			return row.time.Equal(point.Values.Timestamp) && maps.Equal(row.tags, point.Values.Tags)
+		})
+		if i < 0 {
+			f.insert(table, point.Values.Timestamp, point.Values.Tags, fields)
+			continue
+		}
+		maps.Copy(f.tables[table][i].fields, fields)
+	}
+	return nil
+}
+
+func (f *fakeInflux) insert(table string, at time.Time, tags map[string]string, fields map[string]any) {
+	if f.tables == nil {
+		f.tables = map[string][]*fakeRow{}
+	}
+	f.tables[table] = append(f.tables[table], &fakeRow{time: at, tags: tags, fields: fields})
+}
+
+// columns returns the sorted names of the columns of table, empty when the table does not exist.
+func (f *fakeInflux) columns(table string) []string {
+	columns := map[string]bool{}
+	for _, row := range f.tables[table] {
+		for name := range row.values() {
+			columns[name] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(columns))
+}
+
+// row returns the row of table at time with tags, nil when there is none.
+func (f *fakeInflux) row(table string, at time.Time, tags map[string]string) *fakeRow {
+	for _, row := range f.tables[table] {
+		if row.time.Equal(at) && maps.Equal(row.tags, tags) {
+			return row
+		}
+	}
+	return nil
+}
+
+func (r *fakeRow) values() map[string]any {
+	values := map[string]any{"time": r.time}
+	for name, value := range r.tags {
+		values[name] = value
+	}
+	maps.Copy(values, r.fields)
+	return values
+}
+
+// messageReader adapts an ipc.MessageReader to the interface of ipc.NewReaderFromMessageReader.
+type messageReader struct {
+	ipc.MessageReader
+}
+
+func (messageReader) Release() {}
+func (messageReader) Retain()  {}
+
+// queryIterator returns an iterator over rows with columns, as returned by the InfluxDB client. Time is a timestamp
+// column, the other columns are float or string columns depending on their values.
+func queryIterator(columns []string, rows []map[string]any) (*influxdb3.QueryIterator, error) {
+	fields := make([]arrow.Field, 0, len(columns))
+	for _, name := range columns {
+		field := arrow.Field{Name: name, Type: arrow.BinaryTypes.String, Nullable: true}
+		for _, row := range rows {
+			switch row[name].(type) {
+			case time.Time:
+				field.Type = arrow.FixedWidthTypes.Timestamp_ns
+				field.Metadata = arrow.NewMetadata([]string{"iox::column::type"}, []string{"iox::column_type::timestamp"})
+			case float64:
+				field.Type = arrow.PrimitiveTypes.Float64
+			}
+		}
+		fields = append(fields, field)
+	}
+	schema := arrow.NewSchema(fields, nil)
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	for _, row := range rows {
+		for i, name := range columns {
+			switch value := row[name].(type) {
+			case nil:
+				builder.Field(i).AppendNull()
+			case time.Time:
+				builder.Field(i).(*array.TimestampBuilder).Append(arrow.Timestamp(value.UnixNano()))
+			case float64:
+				builder.Field(i).(*array.Float64Builder).Append(value)
+			case string:
+				builder.Field(i).(*array.StringBuilder).Append(value)
+			default:
+				return nil, fmt.Errorf("unsupported value %v of column %s", value, name)
+			}
+		}
+	}
+	record := builder.NewRecordBatch()
+	defer record.Release()
+
+	var buf bytes.Buffer
+	writer := ipc.NewWriter(&buf, ipc.WithSchema(schema))
+	if err := writer.Write(record); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	reader, err := ipc.NewReaderFromMessageReader(messageReader{ipc.NewMessageReader(&buf)})
+	if err != nil {
+		return nil, err
+	}
+	return influxdb3.NewQueryIterator(&flight.Reader{Reader: reader}), nil
+}
+
+// newLegacyInflux returns a fakeInflux holding the rows of the datapoint and datapoint_discarded tables written before
+// tags were mapped to tag columns, at base and the following minutes.
+func newLegacyInflux(base time.Time) *fakeInflux {
+	f := &fakeInflux{}
+	receivedAt := base.Format(time.RFC3339Nano)
+	f.insert(tableDataPoint, base, nil, map[string]any{"value": 1.0, "received_at": receivedAt, "tags": "[a b]"})
+	f.insert(tableDataPoint, base.Add(time.Minute), nil, map[string]any{"value": 2.0, "received_at": receivedAt, "tags": "[]"})
+	f.insert(tableDataPoint, base.Add(2*time.Minute), nil, map[string]any{"value": 3.0, "received_at": receivedAt, "tags": "[c]"})
+	f.insert(tableDataPointDiscarded, base, nil, map[string]any{
+		"value": 4.0, "received_at": receivedAt, "tags": "[a]", "discard_rule": "denied", "discard_reason": "tag_deny",
+	})
+	return f
+}
+
+// TestDataPoint_MigrateTags tests that the legacy rows carrying tags are queried, rewritten with tag columns and then
+// marked as migrated, table by table, and that running the migration again rewrites nothing.
+func TestDataPoint_MigrateTags(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	influx := newLegacyInflux(base)
+	dp := newDataPoint(influx, []string{"a"})
+
+	migrated, err := dp.MigrateTags(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, migrated)
+	assert.Equal(t, []string{
+		"columns datapoint", "select datapoint", "write datapoint 2", "write datapoint 2",
+		"columns datapoint_discarded", "select datapoint_discarded", "write datapoint_discarded 1", "write datapoint_discarded 1",
+	}, influx.calls)
+
+	rewritten := influx.row(tableDataPoint, base, map[string]string{"tag_a": "true", "tagset": ",a,b,"})
+	require.NotNil(t, rewritten)
+	assert.Equal(t, map[string]any{"value": 1.0, "received_at": base.Format(time.RFC3339Nano)}, rewritten.fields)
+	require.NotNil(t, influx.row(tableDataPoint, base.Add(2*time.Minute), map[string]string{"tagset": ",c,"}))
+	discarded := influx.row(tableDataPointDiscarded, base, map[string]string{"tag_a": "true", "tagset": ",a,"})
+	require.NotNil(t, discarded)
+	assert.Equal(t, "tag_deny", discarded.fields["discard_reason"])
+
+	assert.Equal(t, legacyTagsMigrated, influx.row(tableDataPoint, base, nil).fields["tags"])
+	assert.Equal(t, legacyTagsMigrated, influx.row(tableDataPoint, base.Add(2*time.Minute), nil).fields["tags"])
+	assert.Equal(t, legacyTagsMigrated, influx.row(tableDataPointDiscarded, base, nil).fields["tags"])
+	// rows without tags read the same in both layouts
+	assert.Equal(t, "[]", influx.row(tableDataPoint, base.Add(time.Minute), nil).fields["tags"])
+	assert.Len(t, influx.tables[tableDataPoint], 5)
+
+	influx.calls = nil
+	migrated, err = dp.MigrateTags(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, migrated)
+	assert.Equal(t, []string{"columns datapoint", "select datapoint", "columns datapoint_discarded", "select datapoint_discarded"}, influx.calls)
+	assert.Len(t, influx.tables[tableDataPoint], 5)
+}
+
+// TestDataPoint_MigrateTags_Interrupted tests that the legacy rows are not marked when their rewrite fails, and that
+// the migration run again after an interruption rewrites them to the same rows before marking them.
+func TestDataPoint_MigrateTags_Interrupted(t *testing.T) {
+	tests := []struct {
+		name      string
+		failWrite int
+	}{
+		{name: "rewrite", failWrite: 1},
+		{name: "mark", failWrite: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			influx := newLegacyInflux(base)
+			influx.failWrite = tt.failWrite
+			dp := newDataPoint(influx, nil)
+
+			migrated, err := dp.MigrateTags(ctx)
+			require.Error(t, err)
+			assert.Zero(t, migrated)
+			assert.Equal(t, "[a b]", influx.row(tableDataPoint, base, nil).fields["tags"])
+
+			migrated, err = dp.MigrateTags(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 3, migrated)
+			assert.Equal(t, legacyTagsMigrated, influx.row(tableDataPoint, base, nil).fields["tags"])
+			assert.NotNil(t, influx.row(tableDataPoint, base, map[string]string{"tagset": ",a,b,"}))
+			assert.Len(t, influx.tables[tableDataPoint], 5)
+		})
+	}
+}
+
+// TestDataPoint_MigrateTags_Batches tests that the legacy rows are rewritten and marked migrateBatchSize at a time.
+func TestDataPoint_MigrateTags_Batches(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	influx := &fakeInflux{}
+	for i := range migrateBatchSize + 1 {
+		influx.insert(tableDataPoint, base.Add(time.Duration(i)*time.Second), nil, map[string]any{"value": float64(i), "tags": "[a]"})
+	}
+
+	migrated, err := newDataPoint(influx, nil).MigrateTags(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, migrateBatchSize+1, migrated)
+	assert.Equal(t, []string{
+		"columns datapoint", "select datapoint",
+		fmt.Sprintf("write datapoint %d", migrateBatchSize), fmt.Sprintf("write datapoint %d", migrateBatchSize),
+		"write datapoint 1", "write datapoint 1",
+		"columns datapoint_discarded",
+	}, influx.calls)
+}
+
+// TestDataPoint_TagColumnAdded tests that the rows written before a tag was added to the tag columns, which do not have
+// its column set, are still matched on their tag set.
+func TestDataPoint_TagColumnAdded(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	influx := &fakeInflux{}
+	require.NoError(t, newDataPoint(influx, nil).Write(ctx, dto.DataPoint{Time: base, Value: 1, Tags: []string{"a", "b"}}))
+	dp := newDataPoint(influx, []string{"a"})
+	require.NoError(t, dp.Write(ctx, dto.DataPoint{Time: base.Add(time.Minute), Value: 2, Tags: []string{"a"}}))
+
+	columns, err := dp.columns(ctx, tableDataPoint)
+	require.NoError(t, err)
+	conditions, parameters := filterConditions(dto.DataPointFilter{Tags: []string{"a"}}, columns)
+	assert.Equal(t, []string{`("tag_a" IS NOT NULL OR strpos(coalesce("tagset", ''), $tag_0_set) > 0)`}, conditions)
+
+	before := influx.row(tableDataPoint, base, map[string]string{"tagset": ",a,b,"})
+	require.NotNil(t, before)
+	assert.NotContains(t, before.tags, "tag_a")
+	assert.Contains(t, before.tags["tagset"], parameters["tag_0_set"])
+	require.NotNil(t, influx.row(tableDataPoint, base.Add(time.Minute), map[string]string{"tag_a": "true", "tagset": ",a,"}))
+}
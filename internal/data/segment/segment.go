@@ -0,0 +1,222 @@
+package segment
+
+import (
+	"cmp"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"oc-data-be-challenge/internal/data/dto"
+	"os"
+	"slices"
+	"time"
+)
+
+// headerSize is the size of a record header: payload length (uint32), checksum (uint32) and time (int64).
+const headerSize = 16
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// indexEntry locates a record inside a segment file.
+type indexEntry struct {
+	time   int64
+	offset int64
+	length uint32
+}
+
+// segment is a single append-only file holding the records of one time partition.
+//
+// Each record is laid out as:
+//
+//	| length uint32 | crc32c uint32 | time int64 | payload (length bytes) |
+//
+// The checksum covers the time and the payload, all integers are little-endian.
+type segment struct {
+	start int64
+	path  string
+	file  *os.File
+	size  int64
+	// index is sorted by time ascending, records with the same time keep their append order.
+	index []indexEntry
+}
+
+// openSegment opens or creates the segment file at path and rebuilds its index.
+// A torn or corrupted tail left by a crash is truncated, the number of dropped bytes is returned.
+func openSegment(path string, start int64) (*segment, int64, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open segment file: %w", err)
+	}
+
+	seg := &segment{start: start, path: path, file: f}
+	truncated, err := seg.recover()
+	if err != nil {
+		_ = f.Close()
+		return nil, 0, err
+	}
+
+	return seg, truncated, nil
+}
+
+// recover scans the segment from the beginning, indexing every valid record and truncating the file after the last one.
+func (seg *segment) recover() (int64, error) {
+	info, err := seg.file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat segment file: %w", err)
+	}
+
+	header := make([]byte, headerSize)
+	var offset int64
+	for offset < info.Size() {
+		entry, err := seg.readEntry(header, offset, info.Size())
+		if err != nil {
+			break
+		}
+		seg.insert(entry)
+		offset += headerSize + int64(entry.length)
+	}
+
+	truncated := info.Size() - offset
+	if truncated > 0 {
+		if err := seg.file.Truncate(offset); err != nil {
+			return 0, fmt.Errorf("failed to truncate segment file: %w", err)
+		}
+	}
+	seg.size = offset
+
+	return truncated, nil
+}
+
+// readEntry reads and verifies the record at offset, size is the size of the segment file.
+func (seg *segment) readEntry(header []byte, offset, size int64) (indexEntry, error) {
+	if _, err := seg.file.ReadAt(header, offset); err != nil {
+		return indexEntry{}, err
+	}
+
+	length := binary.LittleEndian.Uint32(header[0:4])
+	if offset+headerSize+int64(length) > size {
+		return indexEntry{}, io.ErrUnexpectedEOF
+	}
+	checksum := binary.LittleEndian.Uint32(header[4:8])
+	payload := make([]byte, length)
+	if _, err := seg.file.ReadAt(payload, offset+headerSize); err != nil {
+		return indexEntry{}, err
+	}
+
+	crc := crc32.Update(crc32.Checksum(header[8:16], crcTable), crcTable, payload)
+	if crc != checksum {
+		return indexEntry{}, errors.New("record checksum mismatch")
+	}
+
+	return indexEntry{
+		time:   int64(binary.LittleEndian.Uint64(header[8:16])),
+		offset: offset,
+		length: length,
+	}, nil
+}
+
+// insert adds entry to the index, keeping it ordered by time.
+func (seg *segment) insert(entry indexEntry) {
+	n := len(seg.index)
+	if n == 0 || seg.index[n-1].time <= entry.time {
+		seg.index = append(seg.index, entry)
+		return
+	}
+
+	i, _ := slices.BinarySearchFunc(seg.index, entry.time+1, func(e indexEntry, t int64) int {
+		return cmp.Compare(e.time, t)
+	})
+	seg.index = slices.Insert(seg.index, i, entry)
+}
+
+// append writes point at the end of the segment file.
+func (seg *segment) append(point dto.DataPoint) error {
+	payload, err := json.Marshal(point)
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+
+	record := make([]byte, headerSize+len(payload))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint64(record[8:16], uint64(point.Time.UnixNano()))
+	copy(record[headerSize:], payload)
+	binary.LittleEndian.PutUint32(record[4:8], crc32.Checksum(record[8:], crcTable))
+
+	if _, err := seg.file.WriteAt(record, seg.size); err != nil {
+		// Drop whatever part of the record made it to the file so the next append starts on a record boundary.
+		_ = seg.file.Truncate(seg.size)
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+
+	seg.insert(indexEntry{time: point.Time.UnixNano(), offset: seg.size, length: uint32(len(payload))})
+	seg.size += int64(len(record))
+	return nil
+}
+
+// truncate drops the records written at or after offset size, from the file and from the index.
+func (seg *segment) truncate(size int64) error {
+	seg.index = slices.DeleteFunc(seg.index, func(entry indexEntry) bool {
+		return entry.offset >= size
+	})
+	seg.size = size
+	if err := seg.file.Truncate(size); err != nil {
+		return fmt.Errorf("failed to truncate segment file: %w", err)
+	}
+	return nil
+}
+
+// read decodes the record located by entry.
+func (seg *segment) read(entry indexEntry) (dto.DataPoint, error) {
+	payload := make([]byte, entry.length)
+	if _, err := seg.file.ReadAt(payload, entry.offset+headerSize); err != nil && !errors.Is(err, io.EOF) {
+		return dto.DataPoint{}, fmt.Errorf("failed to read record: %w", err)
+	}
+
+	point := dto.DataPoint{}
+	if err := json.Unmarshal(payload, &point); err != nil {
+		return dto.DataPoint{}, fmt.Errorf("failed to decode record: %w", err)
+	}
+	return point, nil
+}
+
+// scanDesc calls fn for every record within [start, until] in descending time order, until fn returns false.
+func (seg *segment) scanDesc(start, until int64, fn func(dto.DataPoint) bool) (bool, error) {
+	// first index with time > until
+	hi, _ := slices.BinarySearchFunc(seg.index, until+1, func(e indexEntry, t int64) int {
+		return cmp.Compare(e.time, t)
+	})
+	for i := hi - 1; i >= 0 && seg.index[i].time >= start; i-- {
+		point, err := seg.read(seg.index[i])
+		if err != nil {
+			return false, err
+		}
+		if !fn(point) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (seg *segment) close() error {
+	return seg.file.Close()
+}
+
+func (seg *segment) remove() error {
+	if err := seg.file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(seg.path)
+}
+
+// partitionStart returns the start of the partition t belongs to, in unix nanoseconds.
+func partitionStart(t time.Time, duration time.Duration) int64 {
+	ns := t.UnixNano()
+	d := int64(duration)
+	start := ns - ns%d
+	if ns < 0 && ns%d != 0 {
+		start -= d
+	}
+	return start
+}
@@ -0,0 +1,286 @@
+// Package segment implements an embedded storage engine for data points.
+//
+// Data points are appended to time-partitioned segment files, one file per partition, and indexed in memory by
+// timestamp. The index is rebuilt from the segment files when the store is opened, torn records left by a crash are
+// truncated at the same time. Segments older than the retention period are deleted as a whole.
+package segment
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"oc-data-be-challenge/internal/data/dto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// fileExt is the extension of segment files.
+	fileExt = ".seg"
+	// DefaultPartitionDuration is the time span covered by a segment when Options.PartitionDuration is not set.
+	DefaultPartitionDuration = time.Hour
+	// retentionCheckInterval is how often expired segments are looked for.
+	retentionCheckInterval = time.Minute
+)
+
+// Options configures a Store.
+type Options struct {
+	// PartitionDuration is the time span covered by a single segment file.
+	PartitionDuration time.Duration
+	// Retention is how long data points are kept, measured from the end of their partition.
+	// Zero keeps data points forever.
+	Retention time.Duration
+}
+
+// Store is an append-only, time-partitioned data point store persisted in a directory.
+type Store struct {
+	mu       sync.RWMutex
+	dir      string
+	opts     Options
+	segments map[int64]*segment
+	// starts holds the partition start of every open segment, ascending.
+	starts []int64
+	stopCh chan struct{}
+	doneCh chan struct{}
+	logger *slog.Logger
+}
+
+// Open opens the store in dir, creating the directory if needed, and recovers the existing segments.
+func Open(dir string, opts Options) (*Store, error) {
+	if opts.PartitionDuration <= 0 {
+		opts.PartitionDuration = DefaultPartitionDuration
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create segment directory: %w", err)
+	}
+
+	s := &Store{
+		dir:      dir,
+		opts:     opts,
+		segments: map[int64]*segment{},
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+		logger:   slog.With("component", "SegmentStore", "dir", dir),
+	}
+
+	if err := s.load(); err != nil {
+		_ = s.closeSegments()
+		return nil, err
+	}
+
+	if err := s.ApplyRetention(time.Now()); err != nil {
+		_ = s.closeSegments()
+		return nil, err
+	}
+
+	go s.retentionLoop()
+	return s, nil
+}
+
+// load opens every segment file found in the store directory.
+func (s *Store) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to list segment directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), fileExt) {
+			continue
+		}
+
+		start, err := strconv.ParseInt(strings.TrimSuffix(entry.Name(), fileExt), 10, 64)
+		if err != nil {
+			s.logger.Warn("Ignoring unknown file in segment directory", "file", entry.Name())
+			continue
+		}
+
+		seg, truncated, err := openSegment(filepath.Join(s.dir, entry.Name()), start)
+		if err != nil {
+			return fmt.Errorf("failed to recover segment %s: %w", entry.Name(), err)
+		}
+		if truncated > 0 {
+			s.logger.Warn("Truncated torn segment tail", "file", entry.Name(), "bytes", truncated)
+		}
+
+		s.segments[start] = seg
+		s.starts = append(s.starts, start)
+	}
+
+	slices.Sort(s.starts)
+	return nil
+}
+
+// Append persists points in the segments of their partitions. Either all of them are stored or none is: when a point
+// fails to be written, the records already written by the call are dropped, so that the points can be appended again.
+func (s *Store) Append(points ...dto.DataPoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// sizes holds the size of every segment written to before the call
+	sizes := map[*segment]int64{}
+	for _, point := range points {
+		seg, err := s.segmentOf(point.Time)
+		if err == nil {
+			if _, ok := sizes[seg]; !ok {
+				sizes[seg] = seg.size
+			}
+			err = seg.append(point)
+		}
+		if err != nil {
+			for seg, size := range sizes {
+				if truncateErr := seg.truncate(size); truncateErr != nil {
+					err = errors.Join(err, truncateErr)
+				}
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// segmentOf returns the segment of the partition t belongs to, creating it if needed. s.mu must be held.
+func (s *Store) segmentOf(t time.Time) (*segment, error) {
+	start := partitionStart(t, s.opts.PartitionDuration)
+	if seg, ok := s.segments[start]; ok {
+		return seg, nil
+	}
+
+	seg, _, err := openSegment(filepath.Join(s.dir, strconv.FormatInt(start, 10)+fileExt), start)
+	if err != nil {
+		return nil, err
+	}
+	s.segments[start] = seg
+	i, _ := slices.BinarySearch(s.starts, start)
+	s.starts = slices.Insert(s.starts, i, start)
+	return seg, nil
+}
+
+// Range returns the data points within [start, until] ordered by time descending, nil bounds are open.
+// A positive limit caps the number of returned data points.
+func (s *Store) Range(start, until *time.Time, limit int) ([]dto.DataPoint, error) {
+	var result []dto.DataPoint
+	err := s.Scan(start, until, func(point dto.DataPoint) bool {
+		result = append(result, point)
+		return limit <= 0 || len(result) < limit
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Scan calls fn for the data points within [start, until] ordered by time descending, nil bounds are open.
+// Scanning stops when fn returns false.
+func (s *Store) Scan(start, until *time.Time, fn func(dto.DataPoint) bool) error {
+	lo, hi := int64(math.MinInt64), int64(math.MaxInt64-1)
+	if start != nil {
+		lo = start.UnixNano()
+	}
+	if until != nil {
+		hi = until.UnixNano()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := len(s.starts) - 1; i >= 0; i-- {
+		partition := s.starts[i]
+		if partition > hi {
+			continue
+		}
+		if partition+int64(s.opts.PartitionDuration) <= lo {
+			break
+		}
+
+		more, err := s.segments[partition].scanDesc(lo, hi, fn)
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+	}
+
+	return nil
+}
+
+// ApplyRetention deletes the segments whose partition ended before now minus the retention period.
+func (s *Store) ApplyRetention(now time.Time) error {
+	if s.opts.Retention <= 0 {
+		return nil
+	}
+
+	cutoff := now.Add(-s.opts.Retention).UnixNano()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	kept := s.starts[:0]
+	for _, start := range s.starts {
+		if start+int64(s.opts.PartitionDuration) > cutoff {
+			kept = append(kept, start)
+			continue
+		}
+
+		if err := s.segments[start].remove(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove expired segment %d: %w", start, err))
+		} else {
+			s.logger.Info("Removed expired segment", "partition_start", time.Unix(0, start).UTC())
+		}
+		delete(s.segments, start)
+	}
+	s.starts = kept
+
+	return errors.Join(errs...)
+}
+
+func (s *Store) retentionLoop() {
+	defer close(s.doneCh)
+
+	if s.opts.Retention <= 0 {
+		<-s.stopCh
+		return
+	}
+
+	ticker := time.NewTicker(retentionCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.ApplyRetention(time.Now()); err != nil {
+				s.logger.Error("Segment retention error", "error", err)
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// Close stops the retention loop and closes all segment files.
+func (s *Store) Close() error {
+	close(s.stopCh)
+	<-s.doneCh
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeSegments()
+}
+
+func (s *Store) closeSegments() error {
+	var errs []error
+	for _, seg := range s.segments {
+		errs = append(errs, seg.close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,177 @@
+package segment
+
+import (
+	"math"
+	"oc-data-be-challenge/internal/data/dto"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var base = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func values(points []dto.DataPoint) []float64 {
+	result := make([]float64, 0, len(points))
+	for _, point := range points {
+		result = append(result, point.Value)
+	}
+	return result
+}
+
+// TestStore_RangeAcrossPartitions tests range queries spanning several segments, including out-of-order appends.
+func TestStore_RangeAcrossPartitions(t *testing.T) {
+	s, err := Open(t.TempDir(), Options{PartitionDuration: time.Hour})
+	require.NoError(t, err)
+	defer s.Close()
+
+	// Appended out of order on purpose, values are the offset in hours from base.
+	for _, h := range []int{2, 0, 3, 1} {
+		require.NoError(t, s.Append(dto.DataPoint{Time: base.Add(time.Duration(h) * time.Hour), Value: float64(h)}))
+	}
+	require.NoError(t, s.Append(dto.DataPoint{Time: base.Add(90 * time.Minute), Value: 1.5}))
+
+	points, err := s.Range(nil, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{3, 2, 1.5, 1, 0}, values(points))
+
+	start := base.Add(time.Hour)
+	until := base.Add(2 * time.Hour)
+	points, err = s.Range(&start, &until, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{2, 1.5, 1}, values(points))
+
+	points, err = s.Range(nil, nil, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{3, 2}, values(points))
+
+	entries, err := os.ReadDir(s.dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 4, "one segment file per partition")
+}
+
+// TestStore_RecoverTornTail tests that a partially written record is truncated when the store is reopened.
+func TestStore_RecoverTornTail(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(dir, Options{})
+	require.NoError(t, err)
+	require.NoError(t, s.Append(dto.DataPoint{Time: base, Value: 1, Tags: []string{"a"}}))
+	require.NoError(t, s.Append(dto.DataPoint{Time: base.Add(time.Second), Value: 2}))
+	require.NoError(t, s.Close())
+
+	path := filepath.Join(dir, "1735689600000000000"+fileExt)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	validSize := info.Size()
+
+	// Simulate a crash in the middle of writing a third record.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{42, 0, 0, 0, 1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s, err = Open(dir, Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, validSize, info.Size(), "torn tail should be truncated")
+
+	points, err := s.Range(nil, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{2, 1}, values(points))
+	assert.Equal(t, []string{"a"}, points[1].Tags)
+
+	// The store keeps appending on a record boundary after recovery.
+	require.NoError(t, s.Append(dto.DataPoint{Time: base.Add(2 * time.Second), Value: 3}))
+	points, err = s.Range(nil, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{3, 2, 1}, values(points))
+}
+
+// TestStore_RecoverCorruptedRecord tests that everything from a record with a bad checksum onwards is dropped.
+func TestStore_RecoverCorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(dir, Options{})
+	require.NoError(t, err)
+	require.NoError(t, s.Append(dto.DataPoint{Time: base, Value: 1}))
+	require.NoError(t, s.Append(dto.DataPoint{Time: base.Add(time.Second), Value: 2}))
+	require.NoError(t, s.Close())
+
+	path := filepath.Join(dir, "1735689600000000000"+fileExt)
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	b[len(b)-1] ^= 0xFF
+	require.NoError(t, os.WriteFile(path, b, 0o644))
+
+	s, err = Open(dir, Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	points, err := s.Range(nil, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1}, values(points))
+}
+
+// TestStore_AppendBatchFailure tests that a batch failing partway stores none of its points, across partitions and
+// after the store is reopened, so that appending it again does not duplicate them.
+func TestStore_AppendBatchFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(dir, Options{PartitionDuration: time.Hour})
+	require.NoError(t, err)
+	require.NoError(t, s.Append(dto.DataPoint{Time: base, Value: 1}))
+
+	// NaN cannot be encoded, the batch fails on its last point after writing to two partitions
+	batch := []dto.DataPoint{
+		{Time: base.Add(-time.Minute), Value: 2},
+		{Time: base.Add(time.Hour), Value: 3},
+		{Time: base.Add(time.Minute), Value: math.NaN()},
+	}
+	require.Error(t, s.Append(batch...))
+
+	points, err := s.Range(nil, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1}, values(points))
+
+	batch[2].Value = 4
+	require.NoError(t, s.Append(batch...))
+	require.NoError(t, s.Close())
+
+	s, err = Open(dir, Options{PartitionDuration: time.Hour})
+	require.NoError(t, err)
+	defer s.Close()
+	points, err = s.Range(nil, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{3, 4, 1, 2}, values(points))
+}
+
+// TestStore_ApplyRetention tests that whole segments are removed once their partition is older than the retention.
+func TestStore_ApplyRetention(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(dir, Options{PartitionDuration: time.Hour, Retention: 2 * time.Hour})
+	require.NoError(t, err)
+	defer s.Close()
+
+	now := time.Now()
+	require.NoError(t, s.Append(dto.DataPoint{Time: now.Add(-5 * time.Hour), Value: 5}))
+	require.NoError(t, s.Append(dto.DataPoint{Time: now.Add(-30 * time.Minute), Value: 0.5}))
+
+	require.NoError(t, s.ApplyRetention(now))
+
+	points, err := s.Range(nil, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.5}, values(points))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
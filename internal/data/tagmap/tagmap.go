@@ -0,0 +1,110 @@
+// Package tagmap maps the tags of data points to InfluxDB tag columns.
+//
+// Every tag of a data point is stored in the tag set column, see Set. The tags configured as indexed are also stored in
+// their own presence column named after them, e.g. a data point carrying the indexed tag "sensor" is written with the
+// tag column tag_sensor=true and the column is null on data points without the tag. Filters on indexed tags are then
+// evaluated on their own column instead of searching the tag set, while arbitrary tags cannot grow the table with a
+// column each.
+package tagmap
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	// ColumnPrefix prefixes the column of every tag, it keeps tag columns apart from the fields of the table.
+	ColumnPrefix = "tag_"
+	// Present is the value of the column of a tag carried by a data point.
+	Present = "true"
+	// SetColumn is the tag column storing the tag set of a data point, see Set.
+	SetColumn = "tagset"
+)
+
+var (
+	// setEscaper escapes the separator of the tags in a tag set, and the escape character itself.
+	setEscaper = strings.NewReplacer("%", "%25", ",", "%2C")
+	// setUnescaper reverts setEscaper.
+	setUnescaper = strings.NewReplacer("%2C", ",", "%25", "%")
+)
+
+// Column returns the name of the tag column storing tag.
+func Column(tag string) string {
+	return ColumnPrefix + tag
+}
+
+// Columns returns the tag columns of a data point carrying tags, only the tags in indexed get a column. It returns nil
+// when none of the tags is indexed.
+func Columns(tags []string, indexed map[string]bool) map[string]string {
+	var columns map[string]string
+	for _, tag := range tags {
+		if !indexed[tag] {
+			continue
+		}
+		if columns == nil {
+			columns = map[string]string{}
+		}
+		columns[Column(tag)] = Present
+	}
+	return columns
+}
+
+// Tags returns the tags of row, sorted as InfluxDB does not keep the order of tag columns. They are read from the tag
+// set column, or from the tag columns set in row when it was written before the tag set was stored. It returns nil when
+// row carries no tag.
+func Tags(row map[string]any) []string {
+	if set, ok := row[SetColumn].(string); ok && set != "" {
+		return ParseSet(set)
+	}
+
+	var tags []string
+	for name, value := range row {
+		if tag, ok := strings.CutPrefix(name, ColumnPrefix); ok && value != nil {
+			tags = append(tags, tag)
+		}
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+// Set returns the tag set of a data point carrying tags: its tags sorted and deduplicated, each one followed by a comma
+// and the first one preceded by a comma, e.g. ",indoor,sensor,". Commas within tags are escaped. The tag sets of two
+// data points are equal if and only if they carry the same tags. It returns "" when tags is empty.
+func Set(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	sorted := slices.Clone(tags)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	var b strings.Builder
+	b.WriteByte(',')
+	for _, tag := range sorted {
+		b.WriteString(setEscaper.Replace(tag))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// ParseSet returns the tags of the tag set returned by Set, nil when set is empty.
+func ParseSet(set string) []string {
+	var tags []string
+	for _, tag := range strings.Split(strings.Trim(set, ","), ",") {
+		if tag != "" {
+			tags = append(tags, setUnescaper.Replace(tag))
+		}
+	}
+	return tags
+}
+
+// Member returns the substring of the tag set of the data points carrying tag, it is searched for to match them.
+func Member(tag string) string {
+	return "," + setEscaper.Replace(tag) + ","
+}
+
+// Identifier quotes column as an SQL identifier, tag columns may contain any character.
+func Identifier(column string) string {
+	return `"` + strings.ReplaceAll(column, `"`, `""`) + `"`
+}
@@ -0,0 +1,49 @@
+package tagmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestColumns tests that only indexed tags get a column and that the columns written for a data point are read back as
+// its tags.
+func TestColumns(t *testing.T) {
+	indexed := map[string]bool{"sensor": true, "outdoor": true}
+	columns := Columns([]string{"sensor", "outdoor", "unit"}, indexed)
+	assert.Equal(t, map[string]string{"tag_sensor": Present, "tag_outdoor": Present}, columns)
+	assert.Nil(t, Columns([]string{"unit"}, indexed))
+	assert.Nil(t, Columns(nil, indexed))
+
+	row := map[string]any{"value": 1.5, "tag_sensor": Present, "tag_outdoor": Present, "tag_indoor": nil}
+	assert.Equal(t, []string{"outdoor", "sensor"}, Tags(row))
+	assert.Nil(t, Tags(map[string]any{"value": 1.5}))
+
+	// the tag set carries the tags without a column too
+	row = map[string]any{"value": 1.5, "tag_sensor": Present, SetColumn: ",sensor,unit,"}
+	assert.Equal(t, []string{"sensor", "unit"}, Tags(row))
+}
+
+// TestSet tests that the tag set does not depend on the order of the tags and keeps commas within tags apart.
+func TestSet(t *testing.T) {
+	assert.Equal(t, ",indoor,sensor,", Set([]string{"sensor", "indoor", "sensor"}))
+	assert.Equal(t, Set([]string{"a", "b"}), Set([]string{"b", "a"}))
+	assert.NotEqual(t, Set([]string{"a,b"}), Set([]string{"a", "b"}))
+	assert.Equal(t, ",a%2Cb,", Set([]string{"a,b"}))
+	assert.Empty(t, Set(nil))
+}
+
+// TestParseSet tests that a tag set is parsed back to its tags, escaped commas included.
+func TestParseSet(t *testing.T) {
+	tags := []string{"%2C", "a,b", "c"}
+	assert.Equal(t, tags, ParseSet(Set(tags)))
+	assert.Nil(t, ParseSet(""))
+	assert.Contains(t, Set(tags), Member("a,b"))
+	assert.NotContains(t, Set(tags), Member("a"))
+}
+
+// TestIdentifier tests that quotes in column names are escaped.
+func TestIdentifier(t *testing.T) {
+	assert.Equal(t, `"tag_sensor"`, Identifier("tag_sensor"))
+	assert.Equal(t, `"tag_a""b"`, Identifier(`tag_a"b`))
+}
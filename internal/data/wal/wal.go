@@ -0,0 +1,608 @@
+// Package wal implements a durable write-ahead log of collected data points.
+//
+// Entries are appended to numbered segment files and synced to disk before Append returns. Consumers read entries in
+// append order and commit them once they are stored elsewhere, the position of the first uncommitted entry is kept in
+// a checkpoint file so the log resumes where it left off after a restart. Segments whose entries are all committed are
+// deleted, a torn tail left by a crash is truncated when the log is opened.
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"oc-data-be-challenge/internal/data/dto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// fileExt is the extension of segment files.
+	fileExt = ".wal"
+	// checkpointFile is the name of the file holding the position of the first uncommitted entry.
+	checkpointFile = "checkpoint"
+	// headerSize is the size of a record header: payload length (uint32) and checksum (uint32).
+	headerSize = 8
+	// maxRecordSize bounds the payload length of a record, Append rejects larger entries and a larger length read
+	// from a record header is a corrupted header.
+	maxRecordSize = 1 << 20
+	// DefaultMaxSegmentSize is the size in bytes after which a new segment is started when
+	// Options.MaxSegmentSize is not set.
+	DefaultMaxSegmentSize = 16 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrEntryTooLarge is returned by Append for an entry whose encoding exceeds the maximum record size.
+var ErrEntryTooLarge = fmt.Errorf("write-ahead log entry larger than %d bytes", maxRecordSize)
+
+// Options configures a Log.
+type Options struct {
+	// MaxSegmentSize is the size in bytes after which appends go to a new segment file.
+	MaxSegmentSize int64
+	// NoSync skips syncing segment files after every append, entries may be lost if the host crashes.
+	NoSync bool
+}
+
+// Entry is a data point waiting to be written to the storage backend.
+type Entry struct {
+	// Discard tells the data point was dropped by the discard rules, it is written to the discarded data points.
+	Discard bool `json:"discard,omitempty"`
+	// Duplicate tells the data point was collected more than once, it is written to the duplicate data points.
+	Duplicate bool `json:"duplicate,omitempty"`
+	// Point is the collected data point.
+	Point dto.DataPoint `json:"point"`
+	// AppendedAt is the time at which the entry was appended to the log.
+	AppendedAt time.Time `json:"appended_at"`
+}
+
+// Stats describes the entries waiting in the log.
+type Stats struct {
+	// Depth is the number of uncommitted entries.
+	Depth int
+	// Oldest is the time at which the oldest uncommitted entry was appended, zero when the log is empty.
+	Oldest time.Time
+}
+
+// position locates a record: the sequence number of its segment and its offset in the segment file.
+type position struct {
+	seq    uint64
+	offset int64
+}
+
+// Batch is a run of entries read from the log.
+type Batch struct {
+	Entries []Entry
+	// ends holds the position following every entry.
+	ends []position
+}
+
+// Head returns the batch made of the first n entries.
+func (b Batch) Head(n int) Batch {
+	return Batch{Entries: b.Entries[:n], ends: b.ends[:n]}
+}
+
+// Tail returns the batch without its first n entries.
+func (b Batch) Tail(n int) Batch {
+	return Batch{Entries: b.Entries[n:], ends: b.ends[n:]}
+}
+
+// Log is a durable, append-only queue of entries persisted in a directory.
+//
+// Each segment record is laid out as:
+//
+//	| length uint32 | crc32c uint32 | payload (length bytes) |
+//
+// The checksum covers the JSON encoded entry in the payload, all integers are little-endian.
+type Log struct {
+	mu   sync.Mutex
+	dir  string
+	opts Options
+	// seqs holds the sequence number of every segment, ascending. The last one is open for appending.
+	seqs []uint64
+	file *os.File
+	size int64
+	// head is the position of the first uncommitted entry.
+	head   position
+	depth  int
+	logger *slog.Logger
+}
+
+// Open opens the log in dir, creating the directory if needed, and recovers the existing segments.
+func Open(dir string, opts Options) (*Log, error) {
+	if opts.MaxSegmentSize <= 0 {
+		opts.MaxSegmentSize = DefaultMaxSegmentSize
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create write-ahead log directory: %w", err)
+	}
+
+	l := &Log{
+		dir:    dir,
+		opts:   opts,
+		logger: slog.With("component", "WriteAheadLog", "dir", dir),
+	}
+	if err := l.load(); err != nil {
+		if l.file != nil {
+			_ = l.file.Close()
+		}
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// load restores the checkpoint, deletes the committed segments, counts the uncommitted entries and opens the last
+// segment for appending.
+func (l *Log) load() error {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return fmt.Errorf("failed to list write-ahead log directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), fileExt) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), fileExt), 10, 64)
+		if err != nil {
+			l.logger.Warn("Ignoring unknown file in write-ahead log directory", "file", entry.Name())
+			continue
+		}
+		l.seqs = append(l.seqs, seq)
+	}
+	slices.Sort(l.seqs)
+
+	if err := l.readCheckpoint(); err != nil {
+		return err
+	}
+	if err := l.removeCommitted(); err != nil {
+		return err
+	}
+	if len(l.seqs) == 0 {
+		l.seqs = []uint64{l.head.seq}
+	}
+
+	for i, seq := range l.seqs {
+		var start int64
+		if seq == l.head.seq {
+			start = l.head.offset
+		}
+
+		count, end, size, err := l.scan(seq, start)
+		if err != nil {
+			return err
+		}
+		l.depth += count
+
+		if end < size {
+			if i == len(l.seqs)-1 {
+				l.logger.Warn("Truncating torn write-ahead log tail", "file", l.path(seq), "bytes", size-end)
+				if err := os.Truncate(l.path(seq), end); err != nil {
+					return fmt.Errorf("failed to truncate write-ahead log segment: %w", err)
+				}
+			} else {
+				l.logger.Warn("Skipping corrupted write-ahead log records", "file", l.path(seq), "bytes", size-end)
+			}
+		}
+		l.size = end
+	}
+
+	return l.openActive(l.seqs[len(l.seqs)-1])
+}
+
+// scan counts the valid records of segment seq from offset start, corrupted records are skipped. It returns the offset
+// following the last valid record and the size of the segment file.
+func (l *Log) scan(seq uint64, start int64) (int, int64, int64, error) {
+	sr, err := openSegment(l.path(seq), os.O_CREATE|os.O_RDONLY, start)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer sr.close()
+
+	count, end := 0, start
+	for {
+		_, skipped, err := sr.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		if skipped > 0 {
+			l.logger.Warn("Skipping corrupted write-ahead log records", "file", l.path(seq), "offset", end, "bytes", skipped)
+		}
+		count++
+		end = sr.offset
+	}
+
+	return count, end, sr.size, nil
+}
+
+func (l *Log) openActive(seq uint64) error {
+	f, err := os.OpenFile(l.path(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open write-ahead log segment: %w", err)
+	}
+
+	l.file = f
+	return nil
+}
+
+func (l *Log) path(seq uint64) string {
+	return filepath.Join(l.dir, strconv.FormatUint(seq, 10)+fileExt)
+}
+
+// Append durably stores entries at the end of the log in order, the log is synced once for all of them. AppendedAt is
+// set to the current time when it is zero. It returns ErrEntryTooLarge without storing any entry when one of them
+// cannot be read back. When writing fails the entries before the failing one may have been stored.
+func (l *Log) Append(entries ...Entry) error {
+	now := time.Now()
+	records := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		if entry.AppendedAt.IsZero() {
+			entry.AppendedAt = now
+		}
+
+		payload, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode write-ahead log entry: %w", err)
+		}
+		if len(payload) > maxRecordSize {
+			return ErrEntryTooLarge
+		}
+
+		record := make([]byte, headerSize+len(payload))
+		binary.LittleEndian.PutUint32(record[0:4], uint32(len(payload)))
+		binary.LittleEndian.PutUint32(record[4:8], crc32.Checksum(payload, crcTable))
+		copy(record[headerSize:], payload)
+		records = append(records, record)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, record := range records {
+		if l.size > 0 && l.size+int64(len(record)) > l.opts.MaxSegmentSize {
+			if err := l.sync(); err != nil {
+				return err
+			}
+			if err := l.rotate(); err != nil {
+				return err
+			}
+		}
+
+		n, err := l.file.Write(record)
+		if err != nil {
+			// drop the partial record so the next append starts on a record boundary
+			_ = l.file.Truncate(l.size)
+			return fmt.Errorf("failed to append write-ahead log entry: %w", err)
+		}
+		l.size += int64(n)
+		l.depth++
+	}
+	return l.sync()
+}
+
+// sync flushes the active segment to disk unless NoSync is set, l.mu must be held.
+func (l *Log) sync() error {
+	if l.opts.NoSync {
+		return nil
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync write-ahead log segment: %w", err)
+	}
+	return nil
+}
+
+// rotate closes the active segment and starts the next one.
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close write-ahead log segment: %w", err)
+	}
+
+	seq := l.seqs[len(l.seqs)-1] + 1
+	if err := l.openActive(seq); err != nil {
+		return err
+	}
+	l.seqs = append(l.seqs, seq)
+	l.size = 0
+	return nil
+}
+
+// Read returns up to limit uncommitted entries in append order, without committing them.
+func (l *Log) Read(limit int) (Batch, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.read(limit)
+}
+
+func (l *Log) read(limit int) (Batch, error) {
+	var batch Batch
+	pos := l.head
+	for _, seq := range l.seqs {
+		if seq < pos.seq {
+			continue
+		}
+		if seq > pos.seq {
+			pos = position{seq: seq}
+		}
+
+		sr, err := openSegment(l.path(seq), os.O_RDONLY, pos.offset)
+		if err != nil {
+			return Batch{}, err
+		}
+
+		for len(batch.Entries) < limit {
+			// corrupted records are skipped as when the log was opened, they were logged then
+			payload, _, err := sr.next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				sr.close()
+				return Batch{}, err
+			}
+
+			var entry Entry
+			if err := json.Unmarshal(payload, &entry); err != nil {
+				sr.close()
+				return Batch{}, fmt.Errorf("failed to decode write-ahead log entry: %w", err)
+			}
+			pos.offset = sr.offset
+			batch.Entries = append(batch.Entries, entry)
+			batch.ends = append(batch.ends, pos)
+		}
+		sr.close()
+
+		if len(batch.Entries) == limit {
+			break
+		}
+	}
+
+	return batch, nil
+}
+
+// Commit marks the entries of batch as stored, they are not returned by Read anymore. Batches must be committed in
+// the order they were read.
+func (l *Log) Commit(batch Batch) error {
+	if len(batch.ends) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.head = batch.ends[len(batch.ends)-1]
+	l.depth -= len(batch.ends)
+	if err := l.writeCheckpoint(); err != nil {
+		return err
+	}
+	return l.removeCommitted()
+}
+
+// Stats returns the number of uncommitted entries and the append time of the oldest one.
+func (l *Log) Stats() (Stats, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := Stats{Depth: l.depth}
+	if l.depth == 0 {
+		return stats, nil
+	}
+
+	batch, err := l.read(1)
+	if err != nil {
+		return Stats{}, err
+	}
+	if len(batch.Entries) > 0 {
+		stats.Oldest = batch.Entries[0].AppendedAt
+	}
+	return stats, nil
+}
+
+// Close closes the active segment.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
+
+// readCheckpoint restores the head position, it defaults to the start of the first segment.
+func (l *Log) readCheckpoint() error {
+	l.head = position{seq: 1}
+	if len(l.seqs) > 0 {
+		l.head.seq = l.seqs[0]
+	}
+
+	b, err := os.ReadFile(filepath.Join(l.dir, checkpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read write-ahead log checkpoint: %w", err)
+	}
+
+	var cp struct {
+		Segment uint64 `json:"segment"`
+		Offset  int64  `json:"offset"`
+	}
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return fmt.Errorf("failed to decode write-ahead log checkpoint: %w", err)
+	}
+
+	l.head = position{seq: cp.Segment, offset: cp.Offset}
+	return nil
+}
+
+// writeCheckpoint atomically replaces the checkpoint file with the head position.
+func (l *Log) writeCheckpoint() error {
+	b, err := json.Marshal(map[string]any{"segment": l.head.seq, "offset": l.head.offset})
+	if err != nil {
+		return fmt.Errorf("failed to encode write-ahead log checkpoint: %w", err)
+	}
+
+	tmp := filepath.Join(l.dir, checkpointFile+".tmp")
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write write-ahead log checkpoint: %w", err)
+	}
+	_, err = f.Write(b)
+	if err == nil && !l.opts.NoSync {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write write-ahead log checkpoint: %w", err)
+	}
+
+	if err := os.Rename(tmp, filepath.Join(l.dir, checkpointFile)); err != nil {
+		return fmt.Errorf("failed to write write-ahead log checkpoint: %w", err)
+	}
+	return nil
+}
+
+// removeCommitted deletes the segments before the head segment, all their entries are committed.
+func (l *Log) removeCommitted() error {
+	for len(l.seqs) > 0 && l.seqs[0] < l.head.seq {
+		if err := os.Remove(l.path(l.seqs[0])); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove write-ahead log segment: %w", err)
+		}
+		l.seqs = l.seqs[1:]
+	}
+	return nil
+}
+
+// segmentReader reads the records of a segment file in order, skipping the corrupted ones.
+type segmentReader struct {
+	f    *os.File
+	r    *bufio.Reader
+	size int64
+	// offset is the offset of the next record.
+	offset int64
+}
+
+// openSegment opens the segment file at path with flag to read its records from offset start.
+func openSegment(path string, flag int, start int64) (*segmentReader, error) {
+	f, err := os.OpenFile(path, flag, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open write-ahead log segment: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to stat write-ahead log segment: %w", err)
+	}
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to seek write-ahead log segment: %w", err)
+	}
+
+	return &segmentReader{f: f, r: bufio.NewReader(f), size: info.Size(), offset: start}, nil
+}
+
+// next returns the payload of the next valid record and the number of bytes of corrupted records skipped before it.
+// It returns io.EOF once no valid record is left, the bytes following the last valid record are then left unread.
+func (sr *segmentReader) next() ([]byte, int64, error) {
+	payload, err := readRecord(sr.r)
+	if errors.Is(err, io.EOF) {
+		return nil, 0, io.EOF
+	}
+	if err == nil {
+		sr.offset += headerSize + int64(len(payload))
+		return payload, 0, nil
+	}
+
+	// a corrupted record, or a torn one at the end of the segment: resume at the next valid record if any
+	next, err := sr.resync()
+	if err != nil {
+		return nil, 0, err
+	}
+	if next == sr.size {
+		return nil, 0, io.EOF
+	}
+	if _, err := sr.f.Seek(next, io.SeekStart); err != nil {
+		return nil, 0, fmt.Errorf("failed to seek write-ahead log segment: %w", err)
+	}
+	sr.r.Reset(sr.f)
+
+	payload, err = readRecord(sr.r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read write-ahead log record: %w", err)
+	}
+	skipped := next - sr.offset
+	sr.offset = next + headerSize + int64(len(payload))
+	return payload, skipped, nil
+}
+
+// resync returns the offset of the first valid record following the corrupted one at sr.offset, the size of the
+// segment when there is none.
+func (sr *segmentReader) resync() (int64, error) {
+	rest := make([]byte, sr.size-sr.offset)
+	if _, err := sr.f.ReadAt(rest, sr.offset); err != nil && !errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("failed to read write-ahead log segment: %w", err)
+	}
+
+	for i := 1; i < len(rest); i++ {
+		if validRecord(rest[i:]) {
+			return sr.offset + int64(i), nil
+		}
+	}
+	return sr.size, nil
+}
+
+func (sr *segmentReader) close() {
+	_ = sr.f.Close()
+}
+
+// readRecord reads and verifies the next record of r, it returns its payload. It returns io.EOF at the end of r.
+func readRecord(r io.Reader) ([]byte, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	length := binary.LittleEndian.Uint32(header[0:4])
+	if length == 0 || length > maxRecordSize {
+		return nil, errors.New("record length out of range")
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:8]) {
+		return nil, errors.New("record checksum mismatch")
+	}
+
+	return payload, nil
+}
+
+// validRecord reports whether b starts with a complete record whose checksum matches its payload.
+func validRecord(b []byte) bool {
+	if len(b) < headerSize {
+		return false
+	}
+
+	length := binary.LittleEndian.Uint32(b[0:4])
+	if length == 0 || length > maxRecordSize || headerSize+int(length) > len(b) {
+		return false
+	}
+	return crc32.Checksum(b[headerSize:headerSize+length], crcTable) == binary.LittleEndian.Uint32(b[4:8])
+}
@@ -0,0 +1,236 @@
+package wal
+
+import (
+	"oc-data-be-challenge/internal/data/dto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func entry(i int) Entry {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return Entry{
+		Discard:    i%2 == 1,
+		Point:      dto.DataPoint{Time: base.Add(time.Duration(i) * time.Second), Value: float64(i), Tags: []string{"a"}},
+		AppendedAt: base.Add(time.Duration(i) * time.Minute),
+	}
+}
+
+func values(entries []Entry) []float64 {
+	var result []float64
+	for _, e := range entries {
+		result = append(result, e.Point.Value)
+	}
+	return result
+}
+
+// TestLog_AppendReadCommit tests that entries are read in append order until they are committed.
+func TestLog_AppendReadCommit(t *testing.T) {
+	log, err := Open(t.TempDir(), Options{})
+	require.NoError(t, err)
+	defer log.Close()
+
+	for i := range 5 {
+		require.NoError(t, log.Append(entry(i)))
+	}
+
+	batch, err := log.Read(3)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0, 1, 2}, values(batch.Entries))
+	assert.Equal(t, entry(1), batch.Entries[1])
+
+	// reading again without committing returns the same entries
+	again, err := log.Read(3)
+	require.NoError(t, err)
+	assert.Equal(t, batch.Entries, again.Entries)
+
+	require.NoError(t, log.Commit(batch.Head(2)))
+	stats, err := log.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Depth: 3, Oldest: entry(2).AppendedAt}, stats)
+
+	rest, err := log.Read(10)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{2, 3, 4}, values(rest.Entries))
+
+	require.NoError(t, log.Commit(rest))
+	stats, err = log.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, Stats{}, stats)
+}
+
+// TestLog_Reopen tests that the log resumes from the last commit after a restart.
+func TestLog_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	log, err := Open(dir, Options{})
+	require.NoError(t, err)
+	for i := range 4 {
+		require.NoError(t, log.Append(entry(i)))
+	}
+	batch, err := log.Read(2)
+	require.NoError(t, err)
+	require.NoError(t, log.Commit(batch))
+	require.NoError(t, log.Close())
+
+	log, err = Open(dir, Options{})
+	require.NoError(t, err)
+	defer log.Close()
+
+	require.NoError(t, log.Append(entry(4)))
+	stats, err := log.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Depth)
+
+	batch, err = log.Read(10)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{2, 3, 4}, values(batch.Entries))
+}
+
+// TestLog_Rotation tests that segments are rotated and deleted once all their entries are committed.
+func TestLog_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	log, err := Open(dir, Options{MaxSegmentSize: 256})
+	require.NoError(t, err)
+	defer log.Close()
+
+	for i := range 10 {
+		require.NoError(t, log.Append(entry(i)))
+	}
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+fileExt))
+	require.Greater(t, len(segments), 2)
+
+	batch, err := log.Read(10)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, values(batch.Entries))
+
+	require.NoError(t, log.Commit(batch))
+	remaining, _ := filepath.Glob(filepath.Join(dir, "*"+fileExt))
+	assert.Len(t, remaining, 1)
+}
+
+// TestLog_AppendBatch tests that entries appended at once are read in order, even when they span several segments.
+func TestLog_AppendBatch(t *testing.T) {
+	dir := t.TempDir()
+	log, err := Open(dir, Options{MaxSegmentSize: 256})
+	require.NoError(t, err)
+	defer log.Close()
+
+	entries := make([]Entry, 0, 6)
+	for i := range 6 {
+		entries = append(entries, entry(i))
+	}
+	require.NoError(t, log.Append(entries...))
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+fileExt))
+	require.Greater(t, len(segments), 1)
+
+	batch, err := log.Read(10)
+	require.NoError(t, err)
+	assert.Equal(t, entries, batch.Entries)
+}
+
+// TestLog_TornTail tests that a partially written record is truncated when the log is opened.
+func TestLog_TornTail(t *testing.T) {
+	dir := t.TempDir()
+	log, err := Open(dir, Options{})
+	require.NoError(t, err)
+	require.NoError(t, log.Append(entry(0)))
+	require.NoError(t, log.Append(entry(1)))
+	require.NoError(t, log.Close())
+
+	path := filepath.Join(dir, "1"+fileExt)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-3))
+
+	log, err = Open(dir, Options{})
+	require.NoError(t, err)
+	defer log.Close()
+
+	require.NoError(t, log.Append(entry(2)))
+	batch, err := log.Read(10)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0, 2}, values(batch.Entries))
+}
+
+// corruptRecord flips a byte in the payload of the record starting at offset in the segment file at path.
+func corruptRecord(t *testing.T, path string, offset int64) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	require.NoError(t, err)
+	defer f.Close()
+
+	b := make([]byte, 1)
+	_, err = f.ReadAt(b, offset+headerSize+2)
+	require.NoError(t, err)
+	b[0] ^= 0xff
+	_, err = f.WriteAt(b, offset+headerSize+2)
+	require.NoError(t, err)
+}
+
+// TestLog_CorruptedRecord tests that a corrupted record is skipped without losing the records following it, in the
+// segment being appended to as well as in an older one.
+func TestLog_CorruptedRecord(t *testing.T) {
+	for name, rotate := range map[string]bool{"last segment": false, "older segment": true} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "1"+fileExt)
+			log, err := Open(dir, Options{})
+			require.NoError(t, err)
+			require.NoError(t, log.Append(entry(0)))
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			require.NoError(t, log.Append(entry(1), entry(2)))
+			require.NoError(t, log.Close())
+
+			corruptRecord(t, path, info.Size())
+			size, err := os.Stat(path)
+			require.NoError(t, err)
+
+			opts := Options{}
+			if rotate {
+				// every append starts a new segment
+				opts.MaxSegmentSize = 1
+			}
+			log, err = Open(dir, opts)
+			require.NoError(t, err)
+			defer log.Close()
+
+			reopened, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, size.Size(), reopened.Size(), "the records following the corrupted one are kept")
+
+			require.NoError(t, log.Append(entry(3)))
+			stats, err := log.Stats()
+			require.NoError(t, err)
+			assert.Equal(t, 3, stats.Depth)
+
+			batch, err := log.Read(10)
+			require.NoError(t, err)
+			assert.Equal(t, []float64{0, 2, 3}, values(batch.Entries))
+
+			require.NoError(t, log.Commit(batch.Head(2)))
+			batch, err = log.Read(10)
+			require.NoError(t, err)
+			assert.Equal(t, []float64{3}, values(batch.Entries))
+		})
+	}
+}
+
+// TestLog_AppendTooLarge tests that an entry that could not be read back is rejected without storing any entry.
+func TestLog_AppendTooLarge(t *testing.T) {
+	log, err := Open(t.TempDir(), Options{})
+	require.NoError(t, err)
+	defer log.Close()
+
+	large := entry(1)
+	large.Point.Tags = []string{strings.Repeat("a", maxRecordSize)}
+	assert.ErrorIs(t, log.Append(entry(0), large), ErrEntryTooLarge)
+
+	stats, err := log.Stats()
+	require.NoError(t, err)
+	assert.Zero(t, stats.Depth)
+}
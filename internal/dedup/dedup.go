@@ -0,0 +1,124 @@
+// Package dedup detects data points collected more than once, e.g. when a data server is polled faster than it
+// produces data points and returns the same data point on consecutive polls.
+package dedup
+
+import (
+	"math"
+	"oc-data-be-challenge/internal/data/dto"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Action is what is done with a duplicate data point.
+type Action string
+
+const (
+	// ActionSkip drops duplicates.
+	ActionSkip Action = "skip"
+	// ActionStore writes duplicates to their own table, apart from the accepted and discarded data points.
+	ActionStore Action = "store"
+)
+
+// DefaultCacheSize is the number of recent data points remembered when none is configured.
+const DefaultCacheSize = 1024
+
+// Key identifies a data point by its source, time, value and tags. The order of the tags does not matter.
+type Key struct {
+	Source string
+	Time   int64
+	Value  uint64
+	Tags   string
+}
+
+// KeyOf returns the key of point.
+func KeyOf(point dto.DataPoint) Key {
+	tags := slices.Clone(point.Tags)
+	slices.Sort(tags)
+	return Key{
+		Source: point.Source,
+		Time:   point.Time.UnixNano(),
+		Value:  math.Float64bits(point.Value),
+		Tags:   strings.Join(tags, "\x00"),
+	}
+}
+
+// Deduplicator remembers the keys of the most recent data points, bounded to a fixed number of keys so that memory
+// does not grow with the number of collected data points. Once full the oldest key is forgotten first.
+// It is safe for concurrent use.
+type Deduplicator struct {
+	action Action
+
+	mu sync.Mutex
+	// keys maps the remembered keys to their slot in ring.
+	keys map[Key]int
+	// ring holds the remembered keys in the order they were added, next is the slot of the oldest one once it is full.
+	// The slot of a forgotten key keeps it until it is reused.
+	ring       []Key
+	next       int
+	duplicates uint64
+}
+
+// New creates a Deduplicator remembering the last size data points, DefaultCacheSize when size is not positive.
+func New(size int, action Action) *Deduplicator {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+
+	return &Deduplicator{
+		action: action,
+		keys:   make(map[Key]int, size),
+		ring:   make([]Key, 0, size),
+	}
+}
+
+// Action returns what is done with duplicates.
+func (d *Deduplicator) Action() Action {
+	return d.action
+}
+
+// Duplicate reports whether a data point with the same key as point was seen among the remembered ones and counts
+// it. Otherwise point is remembered, forgetting the oldest data point when the cache is full.
+func (d *Deduplicator) Duplicate(point dto.DataPoint) bool {
+	key := KeyOf(point)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.keys[key]; ok {
+		d.duplicates++
+		return true
+	}
+
+	slot := len(d.ring)
+	if slot < cap(d.ring) {
+		d.ring = append(d.ring, key)
+	} else {
+		slot = d.next
+		// the key of the slot may have been forgotten, and remembered again in another slot since
+		if oldest := d.ring[slot]; d.keys[oldest] == slot {
+			delete(d.keys, oldest)
+		}
+		d.ring[slot] = key
+		d.next = (d.next + 1) % len(d.ring)
+	}
+	d.keys[key] = slot
+	return false
+}
+
+// Forget forgets point when it is remembered, e.g. because it could not be stored, so that it is not taken for a
+// duplicate when it is seen again.
+func (d *Deduplicator) Forget(point dto.DataPoint) {
+	key := KeyOf(point)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.keys, key)
+}
+
+// Duplicates returns the number of duplicates seen so far.
+func (d *Deduplicator) Duplicates() uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.duplicates
+}
@@ -0,0 +1,67 @@
+package dedup
+
+import (
+	"oc-data-be-challenge/internal/data/dto"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func point(second int, value float64, tags ...string) dto.DataPoint {
+	return dto.DataPoint{
+		Time:       time.Date(2025, 1, 1, 0, 0, second, 0, time.UTC),
+		Value:      value,
+		Tags:       tags,
+		Source:     "default",
+		ReceivedAt: time.Now(),
+	}
+}
+
+// TestDeduplicator_Duplicate tests that data points are duplicates only when their time, value, tags and source match.
+func TestDeduplicator_Duplicate(t *testing.T) {
+	d := New(10, ActionSkip)
+
+	assert.False(t, d.Duplicate(point(0, 1.5, "a", "b")))
+	assert.True(t, d.Duplicate(point(0, 1.5, "b", "a")), "tag order does not matter")
+	assert.False(t, d.Duplicate(point(1, 1.5, "a", "b")), "different time")
+	assert.False(t, d.Duplicate(point(0, 2.5, "a", "b")), "different value")
+	assert.False(t, d.Duplicate(point(0, 1.5, "a")), "different tags")
+
+	other := point(0, 1.5, "a", "b")
+	other.Source = "other"
+	assert.False(t, d.Duplicate(other), "different source")
+
+	assert.Equal(t, uint64(1), d.Duplicates())
+}
+
+// TestDeduplicator_Bounded tests that the oldest data points are forgotten once the cache is full.
+func TestDeduplicator_Bounded(t *testing.T) {
+	d := New(3, ActionStore)
+
+	for i := range 5 {
+		assert.False(t, d.Duplicate(point(i, 1)))
+	}
+	assert.Len(t, d.keys, 3)
+
+	assert.False(t, d.Duplicate(point(0, 1)), "forgotten")
+	assert.True(t, d.Duplicate(point(4, 1)), "still remembered")
+	assert.Equal(t, uint64(1), d.Duplicates())
+}
+
+// TestDeduplicator_Forget tests that a forgotten data point is not a duplicate, and that the slot it was remembered in
+// does not forget it once it is remembered again.
+func TestDeduplicator_Forget(t *testing.T) {
+	d := New(2, ActionSkip)
+
+	assert.False(t, d.Duplicate(point(0, 1)))
+	d.Forget(point(0, 1))
+	d.Forget(point(1, 1))
+	assert.False(t, d.Duplicate(point(0, 1)), "forgotten")
+	assert.True(t, d.Duplicate(point(0, 1)), "remembered again")
+
+	// reusing the first slot of point 0 keeps it, it is remembered in the second one
+	assert.False(t, d.Duplicate(point(1, 1)))
+	assert.True(t, d.Duplicate(point(0, 1)))
+	assert.Equal(t, uint64(2), d.Duplicates())
+}
@@ -0,0 +1,154 @@
+// Package discard implements the rules deciding which collected data points are discarded instead of stored.
+package discard
+
+import (
+	"errors"
+	"fmt"
+	"oc-data-be-challenge/internal/data/dto"
+	"time"
+)
+
+// Rule types accepted in RuleConfig.Type.
+const (
+	TypeMaxAge     = "max_age"
+	TypeFutureSkew = "future_skew"
+	TypeTagDeny    = "tag_deny"
+	TypeTagAllow   = "tag_allow"
+	TypeValueRange = "value_range"
+	TypeNonFinite  = "non_finite"
+	TypeAll        = "all"
+	TypeAny        = "any"
+	TypeNot        = "not"
+)
+
+// RuleConfig is the declarative configuration of a rule.
+type RuleConfig struct {
+	// Name identifies the rule, it is recorded with the data points the rule discards.
+	// Only required for top-level rules.
+	Name string `json:"name,omitempty"`
+	// Type is the kind of rule, one of the Type* constants.
+	Type string `json:"type"`
+	// MaxAgeMs is the maximum age in milliseconds of a data point, used by "max_age".
+	MaxAgeMs int64 `json:"max_age_ms,omitempty"`
+	// MaxFutureSkewMs is how far in milliseconds a data point may be in the future, used by "future_skew".
+	MaxFutureSkewMs int64 `json:"max_future_skew_ms,omitempty"`
+	// Tags is the tag list used by "tag_deny" and "tag_allow".
+	Tags []string `json:"tags,omitempty"`
+	// Min is the lower value bound used by "value_range".
+	Min *float64 `json:"min,omitempty"`
+	// Max is the upper value bound used by "value_range".
+	Max *float64 `json:"max,omitempty"`
+	// Rules are the composed rules used by "all", "any" and "not" (exactly one rule).
+	Rules []RuleConfig `json:"rules,omitempty"`
+}
+
+// DefaultRuleConfigs returns the rules discarding data points older than one hour or tagged "system" or "suspect".
+func DefaultRuleConfigs() []RuleConfig {
+	return []RuleConfig{
+		{Name: "max_age", Type: TypeMaxAge, MaxAgeMs: time.Hour.Milliseconds()},
+		{Name: "denied_tags", Type: TypeTagDeny, Tags: []string{"system", "suspect"}},
+	}
+}
+
+// Build creates the Rule described by the configuration.
+func (rc RuleConfig) Build() (Rule, error) {
+	switch rc.Type {
+	case TypeMaxAge:
+		if rc.MaxAgeMs <= 0 {
+			return nil, errors.New("max_age_ms must be positive")
+		}
+		return MaxAge(time.Duration(rc.MaxAgeMs) * time.Millisecond), nil
+	case TypeFutureSkew:
+		if rc.MaxFutureSkewMs < 0 {
+			return nil, errors.New("max_future_skew_ms must not be negative")
+		}
+		return FutureSkew(time.Duration(rc.MaxFutureSkewMs) * time.Millisecond), nil
+	case TypeTagDeny:
+		return TagDeny(rc.Tags), nil
+	case TypeTagAllow:
+		return TagAllow(rc.Tags), nil
+	case TypeValueRange:
+		if rc.Min == nil && rc.Max == nil {
+			return nil, errors.New("value_range needs min or max")
+		}
+		if rc.Min != nil && rc.Max != nil && *rc.Min > *rc.Max {
+			return nil, errors.New("value_range min is greater than max")
+		}
+		return ValueRange{Min: rc.Min, Max: rc.Max}, nil
+	case TypeNonFinite:
+		return NonFinite{}, nil
+	case TypeAll, TypeAny:
+		if len(rc.Rules) == 0 {
+			return nil, fmt.Errorf("%s needs at least one rule", rc.Type)
+		}
+		rules := make([]Rule, 0, len(rc.Rules))
+		for i, child := range rc.Rules {
+			rule, err := child.Build()
+			if err != nil {
+				return nil, fmt.Errorf("%s rule %d: %w", rc.Type, i, err)
+			}
+			rules = append(rules, rule)
+		}
+		if rc.Type == TypeAll {
+			return All(rules), nil
+		}
+		return Any(rules), nil
+	case TypeNot:
+		if len(rc.Rules) != 1 {
+			return nil, errors.New("not needs exactly one rule")
+		}
+		rule, err := rc.Rules[0].Build()
+		if err != nil {
+			return nil, fmt.Errorf("not rule: %w", err)
+		}
+		return Not{Rule: rule}, nil
+	default:
+		return nil, fmt.Errorf("unknown rule type %q", rc.Type)
+	}
+}
+
+type namedRule struct {
+	name string
+	rule Rule
+}
+
+// Engine evaluates an ordered list of named rules.
+type Engine struct {
+	rules []namedRule
+	now   func() time.Time
+}
+
+// NewEngine builds an Engine from the given rule configurations, rules are evaluated in order.
+func NewEngine(configs []RuleConfig) (*Engine, error) {
+	engine := &Engine{now: time.Now}
+	names := map[string]struct{}{}
+	for i, rc := range configs {
+		if rc.Name == "" {
+			return nil, fmt.Errorf("discard rule %d has no name", i)
+		}
+		if _, ok := names[rc.Name]; ok {
+			return nil, fmt.Errorf("duplicate discard rule name %q", rc.Name)
+		}
+		names[rc.Name] = struct{}{}
+
+		rule, err := rc.Build()
+		if err != nil {
+			return nil, fmt.Errorf("invalid discard rule %q: %w", rc.Name, err)
+		}
+		engine.rules = append(engine.rules, namedRule{name: rc.Name, rule: rule})
+	}
+
+	return engine, nil
+}
+
+// Evaluate returns the verdict of the first rule matching point, ok is false when the point must be kept.
+func (e *Engine) Evaluate(point dto.DataPoint) (Verdict, bool) {
+	now := e.now()
+	for _, nr := range e.rules {
+		if verdict, ok := nr.rule.Match(point, now); ok {
+			verdict.Rule = nr.name
+			return verdict, true
+		}
+	}
+	return Verdict{}, false
+}
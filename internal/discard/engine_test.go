@@ -0,0 +1,157 @@
+package discard
+
+import (
+	"encoding/json"
+	"math"
+	"oc-data-be-challenge/internal/data/dto"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestEngine(t *testing.T, configs []RuleConfig) *Engine {
+	engine, err := NewEngine(configs)
+	require.NoError(t, err)
+	engine.now = func() time.Time { return now }
+	return engine
+}
+
+// TestEngine_DefaultRules tests that the default rules keep the historical discard behaviour.
+func TestEngine_DefaultRules(t *testing.T) {
+	engine := newTestEngine(t, DefaultRuleConfigs())
+
+	tests := []struct {
+		name    string
+		point   dto.DataPoint
+		discard bool
+		want    Verdict
+	}{
+		{name: "fresh point", point: dto.DataPoint{Time: now.Add(-time.Minute), Tags: []string{"a"}}},
+		{
+			name:    "too old",
+			point:   dto.DataPoint{Time: now.Add(-2 * time.Hour)},
+			discard: true,
+			want:    Verdict{Rule: "max_age", Reason: TypeMaxAge, Detail: "1h0m0s"},
+		},
+		{
+			name:    "system tag",
+			point:   dto.DataPoint{Time: now, Tags: []string{"a", "system"}},
+			discard: true,
+			want:    Verdict{Rule: "denied_tags", Reason: TypeTagDeny, Detail: "system"},
+		},
+		{
+			name:    "suspect tag",
+			point:   dto.DataPoint{Time: now, Tags: []string{"suspect"}},
+			discard: true,
+			want:    Verdict{Rule: "denied_tags", Reason: TypeTagDeny, Detail: "suspect"},
+		},
+		{
+			name:    "first matching rule wins",
+			point:   dto.DataPoint{Time: now.Add(-2 * time.Hour), Tags: []string{"system"}},
+			discard: true,
+			want:    Verdict{Rule: "max_age", Reason: TypeMaxAge, Detail: "1h0m0s"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, ok := engine.Evaluate(tt.point)
+			assert.Equal(t, tt.discard, ok)
+			assert.Equal(t, tt.want, verdict)
+		})
+	}
+}
+
+// TestEngine_RuleTypes tests every rule type decoded from its JSON configuration.
+func TestEngine_RuleTypes(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		point      dto.DataPoint
+		discard    bool
+		wantReason string
+		wantDetail string
+	}{
+		{name: "future skew within limit", config: `{"type":"future_skew","max_future_skew_ms":60000}`, point: dto.DataPoint{Time: now.Add(30 * time.Second)}},
+		{name: "future skew beyond limit", config: `{"type":"future_skew","max_future_skew_ms":60000}`, point: dto.DataPoint{Time: now.Add(2 * time.Minute)}, discard: true, wantReason: TypeFutureSkew, wantDetail: "1m0s"},
+		{name: "tag allow all listed", config: `{"type":"tag_allow","tags":["a","b"]}`, point: dto.DataPoint{Tags: []string{"b", "a"}}},
+		{name: "tag allow unlisted", config: `{"type":"tag_allow","tags":["a","b"]}`, point: dto.DataPoint{Tags: []string{"a", "c"}}, discard: true, wantReason: TypeTagAllow, wantDetail: "c"},
+		{name: "value within range", config: `{"type":"value_range","min":-1,"max":1}`, point: dto.DataPoint{Value: 0.5}},
+		{name: "value below min", config: `{"type":"value_range","min":-1}`, point: dto.DataPoint{Value: -2}, discard: true, wantReason: TypeValueRange, wantDetail: "min=-1"},
+		{name: "value above max", config: `{"type":"value_range","max":1}`, point: dto.DataPoint{Value: 2}, discard: true, wantReason: TypeValueRange, wantDetail: "max=1"},
+		{name: "finite value", config: `{"type":"non_finite"}`, point: dto.DataPoint{Value: 1}},
+		{name: "NaN value", config: `{"type":"non_finite"}`, point: dto.DataPoint{Value: float64(math.NaN())}, discard: true, wantReason: TypeNonFinite, wantDetail: "NaN"},
+		{name: "Inf value", config: `{"type":"non_finite"}`, point: dto.DataPoint{Value: float64(math.Inf(-1))}, discard: true, wantReason: TypeNonFinite, wantDetail: "-Inf"},
+		{
+			name:       "all matches",
+			config:     `{"type":"all","rules":[{"type":"tag_deny","tags":["x"]},{"type":"value_range","max":0}]}`,
+			point:      dto.DataPoint{Value: 1, Tags: []string{"x"}},
+			discard:    true,
+			wantReason: TypeAll,
+			wantDetail: "tag_deny:x,value_range:max=0",
+		},
+		{
+			name:   "all partially matches",
+			config: `{"type":"all","rules":[{"type":"tag_deny","tags":["x"]},{"type":"value_range","max":0}]}`,
+			point:  dto.DataPoint{Value: -1, Tags: []string{"x"}},
+		},
+		{
+			name:       "any matches",
+			config:     `{"type":"any","rules":[{"type":"tag_deny","tags":["x"]},{"type":"value_range","max":0}]}`,
+			point:      dto.DataPoint{Value: -1, Tags: []string{"x"}},
+			discard:    true,
+			wantReason: TypeTagDeny,
+			wantDetail: "x",
+		},
+		{
+			name:       "not inverts",
+			config:     `{"type":"not","rules":[{"type":"tag_deny","tags":["required"]}]}`,
+			point:      dto.DataPoint{Tags: []string{"other"}},
+			discard:    true,
+			wantReason: TypeNot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := RuleConfig{}
+			require.NoError(t, json.Unmarshal([]byte(tt.config), &rc))
+			rc.Name = "rule"
+
+			engine := newTestEngine(t, []RuleConfig{rc})
+			verdict, ok := engine.Evaluate(tt.point)
+			assert.Equal(t, tt.discard, ok)
+			if tt.discard {
+				assert.Equal(t, Verdict{Rule: "rule", Reason: tt.wantReason, Detail: tt.wantDetail}, verdict)
+			}
+		})
+	}
+}
+
+// TestNewEngine_InvalidConfig tests that invalid rule configurations are rejected.
+func TestNewEngine_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []RuleConfig
+	}{
+		{name: "missing name", configs: []RuleConfig{{Type: TypeNonFinite}}},
+		{name: "duplicate name", configs: []RuleConfig{{Name: "a", Type: TypeNonFinite}, {Name: "a", Type: TypeNonFinite}}},
+		{name: "unknown type", configs: []RuleConfig{{Name: "a", Type: "unknown"}}},
+		{name: "non positive max age", configs: []RuleConfig{{Name: "a", Type: TypeMaxAge}}},
+		{name: "value range without bounds", configs: []RuleConfig{{Name: "a", Type: TypeValueRange}}},
+		{name: "empty composition", configs: []RuleConfig{{Name: "a", Type: TypeAny}}},
+		{name: "not with two rules", configs: []RuleConfig{{Name: "a", Type: TypeNot, Rules: []RuleConfig{{Type: TypeNonFinite}, {Type: TypeNonFinite}}}}},
+		{name: "invalid nested rule", configs: []RuleConfig{{Name: "a", Type: TypeAll, Rules: []RuleConfig{{Type: TypeMaxAge}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEngine(tt.configs)
+			assert.Error(t, err)
+		})
+	}
+}
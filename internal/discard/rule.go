@@ -0,0 +1,137 @@
+package discard
+
+import (
+	"math"
+	"oc-data-be-challenge/internal/data/dto"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Verdict describes why a data point is discarded.
+type Verdict struct {
+	// Rule is the name of the top-level rule that matched.
+	Rule string
+	// Reason is the type of the rule that matched, for "any" the type of the first matching nested rule.
+	Reason string
+	// Detail is the matching tag or the exceeded threshold.
+	Detail string
+}
+
+// Rule decides whether a data point must be discarded.
+type Rule interface {
+	// Match reports whether point must be discarded and why, now is the time of the evaluation.
+	Match(point dto.DataPoint, now time.Time) (Verdict, bool)
+}
+
+// MaxAge matches data points older than the given age.
+type MaxAge time.Duration
+
+func (r MaxAge) Match(point dto.DataPoint, now time.Time) (Verdict, bool) {
+	if !point.Time.Before(now.Add(-time.Duration(r))) {
+		return Verdict{}, false
+	}
+	return Verdict{Reason: TypeMaxAge, Detail: time.Duration(r).String()}, true
+}
+
+// FutureSkew matches data points further in the future than the given skew.
+type FutureSkew time.Duration
+
+func (r FutureSkew) Match(point dto.DataPoint, now time.Time) (Verdict, bool) {
+	if !point.Time.After(now.Add(time.Duration(r))) {
+		return Verdict{}, false
+	}
+	return Verdict{Reason: TypeFutureSkew, Detail: time.Duration(r).String()}, true
+}
+
+// TagDeny matches data points carrying any of the listed tags.
+type TagDeny []string
+
+func (r TagDeny) Match(point dto.DataPoint, _ time.Time) (Verdict, bool) {
+	for _, tag := range point.Tags {
+		if slices.Contains(r, tag) {
+			return Verdict{Reason: TypeTagDeny, Detail: tag}, true
+		}
+	}
+	return Verdict{}, false
+}
+
+// TagAllow matches data points carrying a tag that is not listed.
+type TagAllow []string
+
+func (r TagAllow) Match(point dto.DataPoint, _ time.Time) (Verdict, bool) {
+	for _, tag := range point.Tags {
+		if !slices.Contains(r, tag) {
+			return Verdict{Reason: TypeTagAllow, Detail: tag}, true
+		}
+	}
+	return Verdict{}, false
+}
+
+// ValueRange matches data points whose value is outside [Min, Max], nil bounds are open.
+type ValueRange struct {
+	Min *float64
+	Max *float64
+}
+
+func (r ValueRange) Match(point dto.DataPoint, _ time.Time) (Verdict, bool) {
+	v := point.Value
+	if r.Min != nil && v < *r.Min {
+		return Verdict{Reason: TypeValueRange, Detail: "min=" + strconv.FormatFloat(*r.Min, 'g', -1, 64)}, true
+	}
+	if r.Max != nil && v > *r.Max {
+		return Verdict{Reason: TypeValueRange, Detail: "max=" + strconv.FormatFloat(*r.Max, 'g', -1, 64)}, true
+	}
+	return Verdict{}, false
+}
+
+// NonFinite matches data points whose value is NaN or infinite.
+type NonFinite struct{}
+
+func (NonFinite) Match(point dto.DataPoint, _ time.Time) (Verdict, bool) {
+	v := point.Value
+	if !math.IsNaN(v) && !math.IsInf(v, 0) {
+		return Verdict{}, false
+	}
+	return Verdict{Reason: TypeNonFinite, Detail: strconv.FormatFloat(v, 'g', -1, 64)}, true
+}
+
+// All matches when every rule matches.
+type All []Rule
+
+func (r All) Match(point dto.DataPoint, now time.Time) (Verdict, bool) {
+	details := make([]string, 0, len(r))
+	for _, rule := range r {
+		verdict, ok := rule.Match(point, now)
+		if !ok {
+			return Verdict{}, false
+		}
+		details = append(details, verdict.Reason+":"+verdict.Detail)
+	}
+	return Verdict{Reason: TypeAll, Detail: strings.Join(details, ",")}, len(r) > 0
+}
+
+// Any matches when at least one rule matches.
+type Any []Rule
+
+func (r Any) Match(point dto.DataPoint, now time.Time) (Verdict, bool) {
+	for _, rule := range r {
+		if verdict, ok := rule.Match(point, now); ok {
+			return verdict, true
+		}
+	}
+	return Verdict{}, false
+}
+
+// Not matches when the wrapped rule does not match.
+type Not struct {
+	Rule Rule
+}
+
+func (r Not) Match(point dto.DataPoint, now time.Time) (Verdict, bool) {
+	if _, ok := r.Rule.Match(point, now); ok {
+		return Verdict{}, false
+	}
+	return Verdict{Reason: TypeNot}, true
+}
@@ -0,0 +1,113 @@
+// Package health reports whether the service is alive and whether the components it depends on are ready, for the
+// liveness and readiness probes of an orchestrator.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Statuses of a component and of the service.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Check is a named check of a component, Fn returns an error when the component is not ready.
+type Check struct {
+	Name string
+	Fn   func(ctx context.Context) error
+}
+
+// ComponentStatus is the result of the check of a component.
+type ComponentStatus struct {
+	Status string `json:"status"`
+	// Error is why the component is down.
+	Error string `json:"error,omitempty"`
+	// Duration is how long the check took.
+	Duration string `json:"duration"`
+}
+
+// Report is the result of the checks of all components, the service is up when all of them are.
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+}
+
+// Checker runs the checks of the components the service depends on.
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+// NewChecker creates a Checker running checks, every check is bounded by timeout.
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// Check runs all checks concurrently and reports the status of every component.
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Components: make(map[string]ComponentStatus, len(c.checks))}
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status := c.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[check.Name] = status
+			if status.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// run runs check within the timeout of the checker.
+func (c *Checker) run(ctx context.Context, check Check) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Fn(ctx)
+	status := ComponentStatus{Status: StatusUp, Duration: time.Since(start).String()}
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// Handler serves the report of the checks, with status 200 OK when every component is up and 503 Service
+// Unavailable otherwise.
+func (c *Checker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Check(r.Context()))
+	})
+}
+
+// LivenessHandler answers 200 OK as long as the process serves HTTP requests, whatever the status of its components.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, Report{Status: StatusUp})
+	})
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	status := http.StatusOK
+	if report.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
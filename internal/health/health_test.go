@@ -0,0 +1,74 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func up(context.Context) error { return nil }
+
+// TestChecker_Check tests that every component is reported and that a single failing or slow one takes the service
+// down.
+func TestChecker_Check(t *testing.T) {
+	checker := NewChecker(50*time.Millisecond,
+		Check{Name: "storage", Fn: up},
+		Check{Name: "data_server", Fn: func(context.Context) error { return errors.New("connection refused") }},
+		Check{Name: "slow", Fn: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+
+	report := checker.Check(context.Background())
+
+	assert.Equal(t, StatusDown, report.Status)
+	require.Len(t, report.Components, 3)
+	assert.Equal(t, StatusUp, report.Components["storage"].Status)
+	assert.Equal(t, ComponentStatus{Status: StatusDown, Error: "connection refused", Duration: report.Components["data_server"].Duration}, report.Components["data_server"])
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Components["slow"].Error)
+}
+
+// TestChecker_Handler tests that the report is served with 200 OK when every component is up and 503 otherwise.
+func TestChecker_Handler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "up", wantStatus: http.StatusOK},
+		{name: "down", err: errors.New("unreachable"), wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(time.Second, Check{Name: "storage", Fn: func(context.Context) error { return tt.err }})
+
+			rec := httptest.NewRecorder()
+			checker.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			var report Report
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+			assert.Equal(t, tt.name, report.Status)
+			assert.Contains(t, report.Components, "storage")
+		})
+	}
+}
+
+// TestLivenessHandler tests that liveness does not depend on any component.
+func TestLivenessHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"up"}`, rec.Body.String())
+}
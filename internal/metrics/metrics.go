@@ -0,0 +1,118 @@
+// Package metrics holds the Prometheus metrics of the service and serves them in the Prometheus text format.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "oc"
+
+// Outcomes of a data point labelling DataPoints.
+const (
+	OutcomeAccepted  = "accepted"
+	OutcomeDiscarded = "discarded"
+	OutcomeDuplicate = "duplicate"
+	OutcomeInvalid   = "invalid"
+)
+
+// Results of a trigger run labelling TriggerRuns.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Registry holds every metric of the service, along with the Go runtime and process metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// TriggerTicks counts the runs started by every periodic trigger.
+	TriggerTicks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "trigger_ticks_total",
+		Help:      "Number of runs started by a periodic trigger.",
+	}, []string{"trigger"})
+
+	// TriggerRuns counts the finished runs of every periodic trigger by result.
+	TriggerRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "trigger_runs_total",
+		Help:      "Number of runs of a periodic trigger by result, success or failure.",
+	}, []string{"trigger", "result"})
+
+	// UpstreamRequestDuration observes every request made to a data server, retries included.
+	UpstreamRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Latency of the requests made to a data server, until the response headers are received.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"source"})
+
+	// UpstreamResponses counts the answers of every data server by status code, "error" when no response was received.
+	UpstreamResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_responses_total",
+		Help:      "Number of responses of a data server by status code, error when the request failed without a response.",
+	}, []string{"source", "code"})
+
+	// DataPoints counts the collected and pushed data points by outcome.
+	DataPoints = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "datapoints_total",
+		Help:      "Number of data points collected or pushed by outcome: accepted, discarded, duplicate or invalid.",
+	}, []string{"source", "outcome"})
+
+	// DataPointsDiscarded counts the data points dropped by discard rules by reason.
+	DataPointsDiscarded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "datapoints_discarded_total",
+		Help:      "Number of data points dropped by discard rules by reason, e.g. max_age or tag_deny.",
+	}, []string{"source", "reason"})
+
+	// RepositoryDuration observes the writes and queries of the InfluxDB repository.
+	RepositoryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repository_operation_duration_seconds",
+		Help:      "Latency of the InfluxDB writes and queries by operation and table.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "table"})
+
+	// HTTPRequestDuration observes the requests served by the API by route.
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the API requests by method, route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		TriggerTicks,
+		TriggerRuns,
+		UpstreamRequestDuration,
+		UpstreamResponses,
+		DataPoints,
+		DataPointsDiscarded,
+		RepositoryDuration,
+		HTTPRequestDuration,
+	)
+}
+
+// Handler serves the metrics of Registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveSince records the time elapsed since start in observer, e.g. deferred at the start of an operation:
+//
+//	defer metrics.ObserveSince(metrics.RepositoryDuration.WithLabelValues("write", table), time.Now())
+func ObserveSince(observer prometheus.Observer, start time.Time) {
+	observer.Observe(time.Since(start).Seconds())
+}
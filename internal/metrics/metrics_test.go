@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHTTPMiddleware tests that requests are observed by route pattern and status code.
+func TestHTTPMiddleware(t *testing.T) {
+	router := chi.NewRouter()
+	router.With(HTTPMiddleware).Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, id := range []string{"1", "2"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/"+id, nil))
+	}
+
+	assert.Equal(t, 1, testutil.CollectAndCount(HTTPRequestDuration), "one series for both paths")
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `oc_http_request_duration_seconds_count{code="404",method="GET",route="/items/{id}"} 2`)
+}
+
+// TestHandler tests that the metrics are served in the Prometheus text format.
+func TestHandler(t *testing.T) {
+	DataPoints.WithLabelValues("test", OutcomeAccepted).Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `oc_datapoints_total{outcome="accepted",source="test"} 1`)
+	assert.Contains(t, rec.Body.String(), `go_goroutines`)
+}
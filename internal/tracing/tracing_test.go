@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newRecorder registers a tracer provider recording the ended spans in memory for the duration of the test.
+func newRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// TestEnd tests that errors are recorded on the span.
+func TestEnd(t *testing.T) {
+	recorder := newRecorder(t)
+
+	_, span := Start(context.Background(), "failing")
+	End(span, errors.New("boom"))
+	_, span = Start(context.Background(), "succeeding")
+	End(span, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "boom", spans[0].Status().Description)
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+}
+
+// TestPropagation tests that the W3C trace context of a request is continued by the spans started while serving it
+// and injected in outgoing requests.
+func TestPropagation(t *testing.T) {
+	newRecorder(t)
+
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	incoming := http.Header{}
+	Inject(trace.ContextWithRemoteSpanContext(context.Background(), parent), incoming)
+	assert.Equal(t, "00-01000000000000000000000000000000-0200000000000000-01", incoming.Get("traceparent"))
+
+	ctx := Propagator.Extract(context.Background(), propagation.HeaderCarrier(incoming))
+	ctx, span := Start(ctx, "child")
+	defer span.End()
+	assert.Equal(t, parent.TraceID(), span.SpanContext().TraceID())
+
+	outgoing := http.Header{}
+	Inject(ctx, outgoing)
+	assert.Contains(t, outgoing.Get("traceparent"), parent.TraceID().String())
+}
+
+// TestRouteMiddleware tests that the span of a request is named after its route pattern.
+func TestRouteMiddleware(t *testing.T) {
+	recorder := newRecorder(t)
+
+	router := chi.NewRouter()
+	router.With(RouteMiddleware).Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := Start(r.Context(), "http.server")
+		defer span.End()
+		router.ServeHTTP(w, r.WithContext(ctx))
+	})
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /items/{id}", spans[0].Name())
+}
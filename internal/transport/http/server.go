@@ -1,22 +1,46 @@
 package http
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"oc-data-be-challenge/internal/client"
+	"oc-data-be-challenge/internal/collector"
+	"oc-data-be-challenge/internal/data/dto"
+	"oc-data-be-challenge/internal/data/iter"
+	"oc-data-be-challenge/internal/data/wal"
 	"oc-data-be-challenge/internal/usecase"
+	"oc-data-be-challenge/internal/utils/version"
 	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/go-chi/render"
 )
 
+const (
+	// defaultQueryLimit is the page size of GET /data-point when a cursor is given without limit.
+	defaultQueryLimit = 1000
+	// maxQueryLimit is the largest page size accepted by GET /data-point.
+	maxQueryLimit = 10000
+	// headerNextCursor is the response header carrying the cursor of the next page.
+	headerNextCursor = "X-Next-Cursor"
+	// headerAppVersion is the response header identifying the build that served the request.
+	headerAppVersion = "X-App-Version"
+	// minAggregateWindow is the narrowest window accepted by GET /data-point/aggregate.
+	minAggregateWindow = time.Second
+	// maxPushBodySize is the largest body accepted by POST /data-point, in bytes.
+	maxPushBodySize = 10 << 20
+)
+
 type ChiServer struct {
 	dataPointUseCase *usecase.DataPointUseCase
+	// collectors are the data server collectors controlled by the /admin/collector endpoints, by data source name.
+	collectors map[string]*collector.PeriodicTrigger
 }
 
-func NewChiServer(dataPointUseCase *usecase.DataPointUseCase) *ChiServer {
-	return &ChiServer{dataPointUseCase: dataPointUseCase}
+func NewChiServer(dataPointUseCase *usecase.DataPointUseCase, collectors map[string]*collector.PeriodicTrigger) *ChiServer {
+	return &ChiServer{dataPointUseCase: dataPointUseCase, collectors: collectors}
 }
 
 func (chiServer ChiServer) DataPointQuery(w http.ResponseWriter, r *http.Request, params DataPointQueryParams) {
@@ -38,7 +62,50 @@ func (chiServer ChiServer) DataPointQuery(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	resultIter, err := chiServer.dataPointUseCase.Query(r.Context(), start, until)
+	// pages are only returned when asked for, every matching data point is returned otherwise
+	limit := 0
+	if params.Cursor != nil {
+		limit = defaultQueryLimit
+	}
+	if params.Limit != nil {
+		limit = int(*params.Limit)
+		if limit < 1 || limit > maxQueryLimit {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Error{
+				Message: fmt.Sprintf("limit must be between 1 and %d", maxQueryLimit),
+			})
+			return
+		}
+	}
+
+	var after *dto.DataPointCursor
+	if params.Cursor != nil {
+		cursor, err := dto.DecodeDataPointCursor(*params.Cursor)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Error{
+				Message: fmt.Errorf("failed to parse cursor: %v", err).Error(),
+			})
+			return
+		}
+		after = &cursor
+	}
+
+	filter := dto.DataPointFilter{
+		Start:    start,
+		Until:    until,
+		Tags:     optionalSlice(params.Tag),
+		TagsAny:  optionalSlice(params.TagAny),
+		TagsNone: optionalSlice(params.TagNone),
+		Sources:  optionalSlice(params.Source),
+		After:    after,
+	}
+	if limit > 0 {
+		// one extra data point tells whether there is a next page
+		filter.Limit = limit + 1
+	}
+
+	resultIter, err := chiServer.dataPointUseCase.Query(r.Context(), filter)
 	if err != nil {
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, Error{
@@ -47,12 +114,349 @@ func (chiServer ChiServer) DataPointQuery(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	page := make([]dto.DataPoint, 0, limit+1)
+	for resultIter.Next() {
+		dp, err := resultIter.Value()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Error retrieving item", "iter_counter", len(page), "error", err)
+			continue
+		}
+		page = append(page, dp)
+	}
+	if err := resultIter.Err(); err != nil {
+		// a short page would be taken for the last one
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, Error{
+			Message: fmt.Errorf("failed to query datapoints: %v", err).Error(),
+		})
+		return
+	}
+
+	if limit > 0 && len(page) > limit {
+		page = page[:limit]
+		w.Header().Set(headerNextCursor, dto.CursorOf(page[limit-1]).Encode())
+	}
+
+	streamJSONArray(w, r, iter.NewSliceDataPointIter(page), func(dp dto.DataPoint) DataPointModel {
+		return DataPointModel{
+			Time:       dp.Time.Format(time.RFC3339Nano),
+			Value:      dp.Value,
+			Tags:       nonNilTags(dp.Tags),
+			ReceivedAt: dp.ReceivedAt.Format(time.RFC3339Nano),
+			Source:     optionalString(dp.Source),
+		}
+	})
+}
+
+func (chiServer ChiServer) DataPointPush(w http.ResponseWriter, r *http.Request) {
+	body := http.MaxBytesReader(w, r.Body, maxPushBodySize)
+	result, err := chiServer.dataPointUseCase.Push(r.Context(), body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr), errors.Is(err, wal.ErrEntryTooLarge):
+			render.Status(r, http.StatusRequestEntityTooLarge)
+		case errors.Is(err, client.ErrMalformedBody):
+			render.Status(r, http.StatusBadRequest)
+		default:
+			render.Status(r, http.StatusInternalServerError)
+		}
+		render.JSON(w, r, Error{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, PushResultModel{
+		Accepted:   int32(result.Accepted),
+		Discarded:  int32(result.Discarded),
+		Duplicates: int32(result.Duplicates),
+		Invalid:    int32(result.Invalid),
+	})
+}
+
+func (chiServer ChiServer) DataPointQueryDiscarded(w http.ResponseWriter, r *http.Request, params DataPointQueryDiscardedParams) {
+	start, err := chiServer.parseTime(params.Start)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Error{
+			Message: fmt.Errorf("failed to parse start time: %v", err).Error(),
+		})
+		return
+	}
+
+	until, err := chiServer.parseTime(params.Until)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Error{
+			Message: fmt.Errorf("failed to parse until time: %v", err).Error(),
+		})
+		return
+	}
+
+	reason := ""
+	if params.Reason != nil {
+		reason = *params.Reason
+	}
+
+	resultIter, err := chiServer.dataPointUseCase.QueryDiscarded(r.Context(), dto.DataPointFilter{Start: start, Until: until}, reason)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, Error{
+			Message: fmt.Errorf("failed to query discarded datapoints: %v", err).Error(),
+		})
+		return
+	}
+
+	streamJSONArray(w, r, resultIter, func(dp dto.DataPoint) DiscardedDataPointModel {
+		return DiscardedDataPointModel{
+			Time:        dp.Time.Format(time.RFC3339Nano),
+			Value:       dp.Value,
+			Tags:        nonNilTags(dp.Tags),
+			ReceivedAt:  dp.ReceivedAt.Format(time.RFC3339Nano),
+			Source:      optionalString(dp.Source),
+			Rule:        optionalString(dp.DiscardRule),
+			Reason:      optionalString(dp.DiscardReason),
+			Detail:      optionalString(dp.DiscardDetail),
+			DiscardedBy: optionalString(dp.DiscardedBy),
+		}
+	})
+}
+
+func (chiServer ChiServer) DataPointAggregate(w http.ResponseWriter, r *http.Request, params DataPointAggregateParams) {
+	window, err := time.ParseDuration(params.Window)
+	if err != nil || window < minAggregateWindow {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Error{
+			Message: fmt.Sprintf("window must be a duration of at least %s, e.g. 1m or 1h", minAggregateWindow),
+		})
+		return
+	}
+
+	aggregation, err := dto.ParseAggregation(window, params.Fn)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Error{
+			Message: fmt.Errorf("failed to parse fn: %v", err).Error(),
+		})
+		return
+	}
+
+	start, err := chiServer.parseTime(params.Start)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Error{
+			Message: fmt.Errorf("failed to parse start time: %v", err).Error(),
+		})
+		return
+	}
+
+	until, err := chiServer.parseTime(params.Until)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Error{
+			Message: fmt.Errorf("failed to parse until time: %v", err).Error(),
+		})
+		return
+	}
+
+	if err := aggregation.CheckRange(start, until, time.Now()); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Error{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	points, err := chiServer.dataPointUseCase.Aggregate(r.Context(), dto.DataPointFilter{Start: start, Until: until, Sources: optionalSlice(params.Source)}, aggregation)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, Error{
+			Message: fmt.Errorf("failed to aggregate datapoints: %v", err).Error(),
+		})
+		return
+	}
+
+	models := make([]AggregatePointModel, 0, len(points))
+	for _, point := range points {
+		models = append(models, AggregatePointModel{
+			Time:  point.Time.Format(time.RFC3339),
+			Value: point.Value,
+		})
+	}
+	render.JSON(w, r, models)
+}
+
+func (chiServer ChiServer) DataServerCircuitBreaker(w http.ResponseWriter, r *http.Request) {
+	statuses := chiServer.dataPointUseCase.CircuitBreakerStatus()
+	models := make([]CircuitBreakerStatusModel, 0, len(statuses))
+	for _, status := range statuses {
+		model := CircuitBreakerStatusModel{
+			Source:              status.Source,
+			Enabled:             status.Enabled,
+			State:               string(status.State),
+			ConsecutiveFailures: int32(status.ConsecutiveFailures),
+		}
+		if !status.ChangedAt.IsZero() {
+			model.ChangedAt = optionalString(status.ChangedAt.Format(time.RFC3339))
+		}
+		if !status.RetryAt.IsZero() {
+			model.RetryAt = optionalString(status.RetryAt.Format(time.RFC3339))
+		}
+		models = append(models, model)
+	}
+	render.JSON(w, r, models)
+}
+
+func (chiServer ChiServer) QueueStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := chiServer.dataPointUseCase.QueueStatus()
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, Error{
+			Message: fmt.Errorf("failed to read queue status: %v", err).Error(),
+		})
+		return
+	}
+
+	model := QueueStatusModel{
+		Enabled: status.Enabled,
+		Depth:   int64(status.Depth),
+	}
+	if !status.OldestAppendedAt.IsZero() {
+		oldestAgeMs := time.Since(status.OldestAppendedAt).Milliseconds()
+		model.OldestAppendedAt = optionalString(status.OldestAppendedAt.Format(time.RFC3339))
+		model.OldestAgeMs = &oldestAgeMs
+	}
+	render.JSON(w, r, model)
+}
+
+func (chiServer ChiServer) CollectorStatus(w http.ResponseWriter, r *http.Request) {
+	models := make([]CollectorStatusModel, 0, len(chiServer.collectors))
+	for _, source := range chiServer.dataPointUseCase.Sources() {
+		if trigger, ok := chiServer.collectors[source.Name]; ok {
+			models = append(models, toCollectorStatusModel(source.Name, trigger.Status()))
+		}
+	}
+	render.JSON(w, r, models)
+}
+
+func (chiServer ChiServer) CollectorPause(w http.ResponseWriter, r *http.Request, source string) {
+	chiServer.controlCollector(w, r, source, (*collector.PeriodicTrigger).Pause)
+}
+
+func (chiServer ChiServer) CollectorResume(w http.ResponseWriter, r *http.Request, source string) {
+	chiServer.controlCollector(w, r, source, (*collector.PeriodicTrigger).Resume)
+}
+
+func (chiServer ChiServer) CollectorTrigger(w http.ResponseWriter, r *http.Request, source string) {
+	chiServer.controlCollector(w, r, source, func(trigger *collector.PeriodicTrigger) error {
+		// a failed collection is reported by the last_error of the status, the request itself succeeded. The collection
+		// is cancelled with the request, e.g. when the client disconnects.
+		if err := trigger.TriggerNow(r.Context()); errors.Is(err, collector.ErrTriggerStopped) {
+			return err
+		}
+		return nil
+	})
+}
+
+func (chiServer ChiServer) CollectorSetInterval(w http.ResponseWriter, r *http.Request, source string) {
+	var body CollectorSetIntervalJSONRequestBody
+	if err := render.DecodeJSON(r.Body, &body); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Error{
+			Message: fmt.Errorf("failed to decode body: %v", err).Error(),
+		})
+		return
+	}
+	if body.IntervalMs < 1 {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Error{
+			Message: fmt.Sprintf("interval_ms must be at least 1, got %d", body.IntervalMs),
+		})
+		return
+	}
+
+	chiServer.controlCollector(w, r, source, func(trigger *collector.PeriodicTrigger) error {
+		return trigger.SetInterval(time.Millisecond * time.Duration(body.IntervalMs))
+	})
+}
+
+// controlCollector applies control to the collector of source and responds with its status. Controlling a collector
+// that is not started is a conflict.
+func (chiServer ChiServer) controlCollector(w http.ResponseWriter, r *http.Request, source string, control func(*collector.PeriodicTrigger) error) {
+	trigger, ok := chiServer.collectors[source]
+	if !ok {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, Error{
+			Message: fmt.Sprintf("unknown data source %q", source),
+		})
+		return
+	}
+
+	if err := control(trigger); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, collector.ErrTriggerStopped) {
+			status = http.StatusConflict
+		}
+		render.Status(r, status)
+		render.JSON(w, r, Error{
+			Message: fmt.Errorf("failed to control collector of data source %q: %v", source, err).Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, toCollectorStatusModel(source, trigger.Status()))
+}
+
+func toCollectorStatusModel(source string, status collector.TriggerStatus) CollectorStatusModel {
+	model := CollectorStatusModel{
+		Source:     source,
+		State:      string(status.State),
+		IntervalMs: status.Interval.Milliseconds(),
+		RunCount:   status.Runs,
+	}
+	if !status.LastRunAt.IsZero() {
+		model.LastRunAt = optionalString(status.LastRunAt.Format(time.RFC3339))
+	}
+	if status.LastError != nil {
+		model.LastError = optionalString(status.LastError.Error())
+	}
+	return model
+}
+
+func (chiServer ChiServer) VersionInfo(w http.ResponseWriter, r *http.Request) {
+	info := version.BuildInfo{}.Info()
+	render.JSON(w, r, VersionModel{
+		Tag:          info.Tag,
+		Revision:     info.Revision,
+		BuildDate:    info.BuildDate,
+		BuildMachine: info.BuildHost,
+		BuildUser:    info.BuildUser,
+		IsTaint:      info.IsTaint,
+		GoArch:       info.GoArch,
+		GoOs:         info.GoOS,
+		GoVersion:    info.GoVersion,
+	})
+}
+
+// VersionHeader sets the X-App-Version header of every response to the version of the build, see
+// version.BuildInfo.Version.
+func VersionHeader(next http.Handler) http.Handler {
+	appVersion := version.BuildInfo{}.Info().Version()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerAppVersion, appVersion)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// streamJSONArray streams the items of resultIter as a JSON array, each item is converted with toModel.
+func streamJSONArray[T any](w http.ResponseWriter, r *http.Request, resultIter iter.DataPointIterator, toModel func(dto.DataPoint) T) {
 	// Set response header for JSON content
 	w.Header().Set("Content-Type", "application/json")
 
 	// Stream the JSON array directly to the response writer
 	resBodyEncoder := sonic.Config{NoEncoderNewline: true}.Froze().NewEncoder(w)
-	_, err = w.Write([]byte("["))
+	_, err := w.Write([]byte("["))
 	if err != nil {
 		slog.ErrorContext(r.Context(), "Error writing response start", "error", err)
 		return
@@ -76,15 +480,17 @@ func (chiServer ChiServer) DataPointQuery(w http.ResponseWriter, r *http.Request
 		}
 
 		// Encode the item directly to the response writer
-		if err := resBodyEncoder.Encode(DataPointModel{
-			Time:  dp.Time.Format(time.RFC3339),
-			Value: dp.Value,
-		}); err != nil {
+		if err := resBodyEncoder.Encode(toModel(dp)); err != nil {
 			slog.ErrorContext(r.Context(), "Error encoding response item", "error", err)
 			return
 		}
 		i++
 	}
+	if err := resultIter.Err(); err != nil {
+		// the status is already sent, the array is left unterminated so that the response is not taken for complete
+		slog.ErrorContext(r.Context(), "Error iterating items", "iter_counter", i, "error", err)
+		return
+	}
 	_, err = w.Write([]byte("]"))
 	if err != nil {
 		slog.ErrorContext(r.Context(), "Error writing response end", "error", err)
@@ -102,3 +508,27 @@ func (chiServer ChiServer) parseTime(timeStr *string) (*time.Time, error) {
 	}
 	return &t, nil
 }
+
+// optionalString returns nil for an empty string, so it is omitted from the response.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// optionalSlice returns the values of an optional list parameter, nil when it is absent.
+func optionalSlice(values *[]string) []string {
+	if values == nil {
+		return nil
+	}
+	return *values
+}
+
+// nonNilTags returns an empty slice instead of nil, so tags are always encoded as a JSON array.
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
@@ -4,17 +4,106 @@
 package http
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/oapi-codegen/runtime"
 )
 
+// AggregatePointModel defines model for AggregatePointModel.
+type AggregatePointModel struct {
+	// Time Start of the window.
+	Time string `json:"time"`
+
+	// Value Aggregated value of the window.
+	Value float64 `json:"value"`
+}
+
+// CircuitBreakerStatusModel defines model for CircuitBreakerStatusModel.
+type CircuitBreakerStatusModel struct {
+	// ChangedAt Time of the last state change, absent if the circuit breaker never opened.
+	ChangedAt *string `json:"changed_at,omitempty"`
+
+	// ConsecutiveFailures Number of failed polls since the last successful one.
+	ConsecutiveFailures int32 `json:"consecutive_failures"`
+
+	// Enabled Whether the circuit breaker is enabled.
+	Enabled bool `json:"enabled"`
+
+	// RetryAt Time after which the data server is probed again, only present while the circuit breaker is open.
+	RetryAt *string `json:"retry_at,omitempty"`
+
+	// Source Name of the data source.
+	Source string `json:"source"`
+
+	// State State of the circuit breaker: closed, open or half_open.
+	State string `json:"state"`
+}
+
+// CollectorIntervalModel defines model for CollectorIntervalModel.
+type CollectorIntervalModel struct {
+	// IntervalMs Interval in milliseconds between two collections.
+	IntervalMs int64 `json:"interval_ms"`
+}
+
+// CollectorStatusModel defines model for CollectorStatusModel.
+type CollectorStatusModel struct {
+	// IntervalMs Interval in milliseconds between two collections.
+	IntervalMs int64 `json:"interval_ms"`
+
+	// LastError Error of the last collection, absent when it succeeded.
+	LastError *string `json:"last_error,omitempty"`
+
+	// LastRunAt Start time of the last collection, absent before the first one.
+	LastRunAt *string `json:"last_run_at,omitempty"`
+
+	// RunCount Number of collections run since the service started.
+	RunCount int64 `json:"run_count"`
+
+	// Source Name of the data source.
+	Source string `json:"source"`
+
+	// State State of the collector: running, paused or stopped.
+	State string `json:"state"`
+}
+
 // DataPointModel defines model for DataPointModel.
 type DataPointModel struct {
-	Time  string  `json:"time"`
-	Value float32 `json:"value"`
+	// ReceivedAt Time at which the collector received the data point.
+	ReceivedAt string `json:"received_at"`
+
+	// Source Name of the data source the data point was collected from.
+	Source *string  `json:"source,omitempty"`
+	Tags   []string `json:"tags"`
+	Time   string   `json:"time"`
+	Value  float64  `json:"value"`
+}
+
+// DiscardedDataPointModel defines model for DiscardedDataPointModel.
+type DiscardedDataPointModel struct {
+	// Detail Tag or threshold that caused the data point to be dropped.
+	Detail *string `json:"detail,omitempty"`
+
+	// DiscardedBy Collector instance that dropped the data point.
+	DiscardedBy *string `json:"discarded_by,omitempty"`
+
+	// Reason Type of check that dropped the data point, e.g. max_age or tag_deny.
+	Reason *string `json:"reason,omitempty"`
+
+	// ReceivedAt Time at which the collector received the data point.
+	ReceivedAt string `json:"received_at"`
+
+	// Rule Name of the discard rule that dropped the data point.
+	Rule *string `json:"rule,omitempty"`
+
+	// Source Name of the data source the data point was collected from.
+	Source *string  `json:"source,omitempty"`
+	Tags   []string `json:"tags"`
+	Time   string   `json:"time"`
+	Value  float64  `json:"value"`
 }
 
 // Error defines model for Error.
@@ -22,28 +111,308 @@ type Error struct {
 	Message string `json:"message"`
 }
 
+// PushDataPointModel defines model for PushDataPointModel.
+type PushDataPointModel struct {
+	Tags []string `json:"tags"`
+
+	// Time Unix timestamp in seconds, milliseconds, microseconds or nanoseconds, detected from its magnitude, or RFC3339 string.
+	Time PushDataPointModel_Time `json:"time"`
+
+	// Value Value as the base64 encoded bytes of a little-endian float32.
+	Value []byte `json:"value"`
+}
+
+// PushDataPointModelTime0 defines model for .
+type PushDataPointModelTime0 = int64
+
+// PushDataPointModelTime1 defines model for .
+type PushDataPointModelTime1 = time.Time
+
+// PushDataPointModel_Time Unix timestamp in seconds, milliseconds, microseconds or nanoseconds, detected from its magnitude, or RFC3339 string.
+type PushDataPointModel_Time struct {
+	union json.RawMessage
+}
+
+// PushResultModel defines model for PushResultModel.
+type PushResultModel struct {
+	// Accepted Number of data points stored as accepted.
+	Accepted int32 `json:"accepted"`
+
+	// Discarded Number of data points dropped by discard rules.
+	Discarded int32 `json:"discarded"`
+
+	// Duplicates Number of data points already received, skipped or stored as duplicates.
+	Duplicates int32 `json:"duplicates"`
+
+	// Invalid Number of data points that failed to decode or to validate, they are skipped.
+	Invalid int32 `json:"invalid"`
+}
+
+// QueueStatusModel defines model for QueueStatusModel.
+type QueueStatusModel struct {
+	// Depth Number of collected data points waiting to be written to the storage backend.
+	Depth int64 `json:"depth"`
+
+	// Enabled Whether collected data points go through the write-ahead log.
+	Enabled bool `json:"enabled"`
+
+	// OldestAgeMs Age in milliseconds of the oldest waiting data point, absent when none is waiting.
+	OldestAgeMs *int64 `json:"oldest_age_ms,omitempty"`
+
+	// OldestAppendedAt Time at which the oldest waiting data point was queued, absent when none is waiting.
+	OldestAppendedAt *string `json:"oldest_appended_at,omitempty"`
+}
+
+// VersionModel defines model for VersionModel.
+type VersionModel struct {
+	// BuildDate Date and time the build was created.
+	BuildDate string `json:"build_date"`
+
+	// BuildMachine Host on which the build was created.
+	BuildMachine string `json:"build_machine"`
+
+	// BuildUser User who created the build.
+	BuildUser string `json:"build_user"`
+
+	// GoArch CPU architecture of the binary.
+	GoArch string `json:"go_arch"`
+
+	// GoOs Operating system of the binary.
+	GoOs string `json:"go_os"`
+
+	// GoVersion Go version the binary was compiled with.
+	GoVersion string `json:"go_version"`
+
+	// IsTaint 1 when the build has uncommitted changes, 0 otherwise.
+	IsTaint string `json:"is_taint"`
+
+	// Revision Git commit of the build.
+	Revision string `json:"revision"`
+
+	// Tag Git tag of the build.
+	Tag string `json:"tag"`
+}
+
 // DataPointQueryParams defines parameters for DataPointQuery.
 type DataPointQueryParams struct {
 	Start *string `form:"start,omitempty" json:"start,omitempty"`
 	Until *string `form:"until,omitempty" json:"until,omitempty"`
+
+	// Tag Only return data points carrying all of these tags.
+	Tag *[]string `form:"tag,omitempty" json:"tag,omitempty"`
+
+	// TagAny Only return data points carrying at least one of these tags.
+	TagAny *[]string `form:"tag_any,omitempty" json:"tag_any,omitempty"`
+
+	// TagNone Only return data points carrying none of these tags.
+	TagNone *[]string `form:"tag_none,omitempty" json:"tag_none,omitempty"`
+
+	// Source Only return data points collected from one of these data sources.
+	Source *[]string `form:"source,omitempty" json:"source,omitempty"`
+
+	// Limit Maximum number of data points returned, defaults to 1000 with a cursor and to all of them otherwise.
+	Limit *int32 `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Cursor Opaque cursor returned in the X-Next-Cursor header of the previous page.
+	Cursor *string `form:"cursor,omitempty" json:"cursor,omitempty"`
+}
+
+// DataPointPushJSONBody defines parameters for DataPointPush.
+type DataPointPushJSONBody = []PushDataPointModel
+
+// DataPointAggregateParams defines parameters for DataPointAggregate.
+type DataPointAggregateParams struct {
+	// Window Width of the windows, e.g. 1m or 1h.
+	Window string `form:"window" json:"window"`
+
+	// Fn Aggregate function: mean, min, max, sum, count, first, last, or a percentile such as p95.
+	Fn    string  `form:"fn" json:"fn"`
+	Start *string `form:"start,omitempty" json:"start,omitempty"`
+	Until *string `form:"until,omitempty" json:"until,omitempty"`
+
+	// Source Only aggregate data points collected from one of these data sources.
+	Source *[]string `form:"source,omitempty" json:"source,omitempty"`
+}
+
+// DataPointQueryDiscardedParams defines parameters for DataPointQueryDiscarded.
+type DataPointQueryDiscardedParams struct {
+	Start  *string `form:"start,omitempty" json:"start,omitempty"`
+	Until  *string `form:"until,omitempty" json:"until,omitempty"`
+	Reason *string `form:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// CollectorSetIntervalJSONRequestBody defines body for CollectorSetInterval for application/json ContentType.
+type CollectorSetIntervalJSONRequestBody = CollectorIntervalModel
+
+// DataPointPushJSONRequestBody defines body for DataPointPush for application/json ContentType.
+type DataPointPushJSONRequestBody = DataPointPushJSONBody
+
+// AsPushDataPointModelTime0 returns the union data inside the PushDataPointModel_Time as a PushDataPointModelTime0
+func (t PushDataPointModel_Time) AsPushDataPointModelTime0() (PushDataPointModelTime0, error) {
+	var body PushDataPointModelTime0
+	err := json.Unmarshal(t.union, &body)
+	return body, err
+}
+
+// FromPushDataPointModelTime0 overwrites any union data inside the PushDataPointModel_Time as the provided PushDataPointModelTime0
+func (t *PushDataPointModel_Time) FromPushDataPointModelTime0(v PushDataPointModelTime0) error {
+	b, err := json.Marshal(v)
+	t.union = b
+	return err
+}
+
+// MergePushDataPointModelTime0 performs a merge with any union data inside the PushDataPointModel_Time, using the provided PushDataPointModelTime0
+func (t *PushDataPointModel_Time) MergePushDataPointModelTime0(v PushDataPointModelTime0) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	merged, err := runtime.JSONMerge(t.union, b)
+	t.union = merged
+	return err
+}
+
+// AsPushDataPointModelTime1 returns the union data inside the PushDataPointModel_Time as a PushDataPointModelTime1
+func (t PushDataPointModel_Time) AsPushDataPointModelTime1() (PushDataPointModelTime1, error) {
+	var body PushDataPointModelTime1
+	err := json.Unmarshal(t.union, &body)
+	return body, err
+}
+
+// FromPushDataPointModelTime1 overwrites any union data inside the PushDataPointModel_Time as the provided PushDataPointModelTime1
+func (t *PushDataPointModel_Time) FromPushDataPointModelTime1(v PushDataPointModelTime1) error {
+	b, err := json.Marshal(v)
+	t.union = b
+	return err
+}
+
+// MergePushDataPointModelTime1 performs a merge with any union data inside the PushDataPointModel_Time, using the provided PushDataPointModelTime1
+func (t *PushDataPointModel_Time) MergePushDataPointModelTime1(v PushDataPointModelTime1) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	merged, err := runtime.JSONMerge(t.union, b)
+	t.union = merged
+	return err
+}
+
+func (t PushDataPointModel_Time) MarshalJSON() ([]byte, error) {
+	b, err := t.union.MarshalJSON()
+	return b, err
+}
+
+func (t *PushDataPointModel_Time) UnmarshalJSON(b []byte) error {
+	err := t.union.UnmarshalJSON(b)
+	return err
 }
 
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
 
+	// (GET /admin/collector)
+	CollectorStatus(w http.ResponseWriter, r *http.Request)
+
+	// (PUT /admin/collector/{source}/interval)
+	CollectorSetInterval(w http.ResponseWriter, r *http.Request, source string)
+
+	// (POST /admin/collector/{source}/pause)
+	CollectorPause(w http.ResponseWriter, r *http.Request, source string)
+
+	// (POST /admin/collector/{source}/resume)
+	CollectorResume(w http.ResponseWriter, r *http.Request, source string)
+
+	// (POST /admin/collector/{source}/trigger)
+	CollectorTrigger(w http.ResponseWriter, r *http.Request, source string)
+
 	// (GET /data-point)
 	DataPointQuery(w http.ResponseWriter, r *http.Request, params DataPointQueryParams)
+
+	// (POST /data-point)
+	DataPointPush(w http.ResponseWriter, r *http.Request)
+
+	// (GET /data-point/aggregate)
+	DataPointAggregate(w http.ResponseWriter, r *http.Request, params DataPointAggregateParams)
+
+	// (GET /data-point/discarded)
+	DataPointQueryDiscarded(w http.ResponseWriter, r *http.Request, params DataPointQueryDiscardedParams)
+
+	// (GET /data-server/circuit-breaker)
+	DataServerCircuitBreaker(w http.ResponseWriter, r *http.Request)
+
+	// (GET /queue)
+	QueueStatus(w http.ResponseWriter, r *http.Request)
+
+	// (GET /version)
+	VersionInfo(w http.ResponseWriter, r *http.Request)
 }
 
 // Unimplemented server implementation that returns http.StatusNotImplemented for each endpoint.
 
 type Unimplemented struct{}
 
+// (GET /admin/collector)
+func (_ Unimplemented) CollectorStatus(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// (PUT /admin/collector/{source}/interval)
+func (_ Unimplemented) CollectorSetInterval(w http.ResponseWriter, r *http.Request, source string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// (POST /admin/collector/{source}/pause)
+func (_ Unimplemented) CollectorPause(w http.ResponseWriter, r *http.Request, source string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// (POST /admin/collector/{source}/resume)
+func (_ Unimplemented) CollectorResume(w http.ResponseWriter, r *http.Request, source string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// (POST /admin/collector/{source}/trigger)
+func (_ Unimplemented) CollectorTrigger(w http.ResponseWriter, r *http.Request, source string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // (GET /data-point)
 func (_ Unimplemented) DataPointQuery(w http.ResponseWriter, r *http.Request, params DataPointQueryParams) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// (POST /data-point)
+func (_ Unimplemented) DataPointPush(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// (GET /data-point/aggregate)
+func (_ Unimplemented) DataPointAggregate(w http.ResponseWriter, r *http.Request, params DataPointAggregateParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// (GET /data-point/discarded)
+func (_ Unimplemented) DataPointQueryDiscarded(w http.ResponseWriter, r *http.Request, params DataPointQueryDiscardedParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// (GET /data-server/circuit-breaker)
+func (_ Unimplemented) DataServerCircuitBreaker(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// (GET /queue)
+func (_ Unimplemented) QueueStatus(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// (GET /version)
+func (_ Unimplemented) VersionInfo(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // ServerInterfaceWrapper converts contexts to parameters.
 type ServerInterfaceWrapper struct {
 	Handler            ServerInterface
@@ -53,6 +422,120 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(http.Handler) http.Handler
 
+// CollectorStatus operation middleware
+func (siw *ServerInterfaceWrapper) CollectorStatus(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CollectorStatus(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// CollectorSetInterval operation middleware
+func (siw *ServerInterfaceWrapper) CollectorSetInterval(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "source" -------------
+	var source string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "source", chi.URLParam(r, "source"), &source, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "source", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CollectorSetInterval(w, r, source)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// CollectorPause operation middleware
+func (siw *ServerInterfaceWrapper) CollectorPause(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "source" -------------
+	var source string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "source", chi.URLParam(r, "source"), &source, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "source", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CollectorPause(w, r, source)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// CollectorResume operation middleware
+func (siw *ServerInterfaceWrapper) CollectorResume(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "source" -------------
+	var source string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "source", chi.URLParam(r, "source"), &source, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "source", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CollectorResume(w, r, source)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// CollectorTrigger operation middleware
+func (siw *ServerInterfaceWrapper) CollectorTrigger(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "source" -------------
+	var source string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "source", chi.URLParam(r, "source"), &source, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "source", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CollectorTrigger(w, r, source)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 // DataPointQuery operation middleware
 func (siw *ServerInterfaceWrapper) DataPointQuery(w http.ResponseWriter, r *http.Request) {
 
@@ -77,6 +560,54 @@ func (siw *ServerInterfaceWrapper) DataPointQuery(w http.ResponseWriter, r *http
 		return
 	}
 
+	// ------------- Optional query parameter "tag" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "tag", r.URL.Query(), &params.Tag)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "tag", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "tag_any" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "tag_any", r.URL.Query(), &params.TagAny)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "tag_any", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "tag_none" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "tag_none", r.URL.Query(), &params.TagNone)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "tag_none", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "source" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "source", r.URL.Query(), &params.Source)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "source", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", false, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "cursor" -------------
+
+	err = runtime.BindQueryParameter("form", false, false, "cursor", r.URL.Query(), &params.Cursor)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "cursor", Err: err})
+		return
+	}
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.DataPointQuery(w, r, params)
 	}))
@@ -88,6 +619,178 @@ func (siw *ServerInterfaceWrapper) DataPointQuery(w http.ResponseWriter, r *http
 	handler.ServeHTTP(w, r)
 }
 
+// DataPointPush operation middleware
+func (siw *ServerInterfaceWrapper) DataPointPush(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DataPointPush(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DataPointAggregate operation middleware
+func (siw *ServerInterfaceWrapper) DataPointAggregate(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DataPointAggregateParams
+
+	// ------------- Required query parameter "window" -------------
+
+	if paramValue := r.URL.Query().Get("window"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "window"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", false, true, "window", r.URL.Query(), &params.Window)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "window", Err: err})
+		return
+	}
+
+	// ------------- Required query parameter "fn" -------------
+
+	if paramValue := r.URL.Query().Get("fn"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "fn"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", false, true, "fn", r.URL.Query(), &params.Fn)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "fn", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "start" -------------
+
+	err = runtime.BindQueryParameter("form", false, false, "start", r.URL.Query(), &params.Start)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "start", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "until" -------------
+
+	err = runtime.BindQueryParameter("form", false, false, "until", r.URL.Query(), &params.Until)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "until", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "source" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "source", r.URL.Query(), &params.Source)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "source", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DataPointAggregate(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DataPointQueryDiscarded operation middleware
+func (siw *ServerInterfaceWrapper) DataPointQueryDiscarded(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DataPointQueryDiscardedParams
+
+	// ------------- Optional query parameter "start" -------------
+
+	err = runtime.BindQueryParameter("form", false, false, "start", r.URL.Query(), &params.Start)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "start", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "until" -------------
+
+	err = runtime.BindQueryParameter("form", false, false, "until", r.URL.Query(), &params.Until)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "until", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "reason" -------------
+
+	err = runtime.BindQueryParameter("form", false, false, "reason", r.URL.Query(), &params.Reason)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "reason", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DataPointQueryDiscarded(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DataServerCircuitBreaker operation middleware
+func (siw *ServerInterfaceWrapper) DataServerCircuitBreaker(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DataServerCircuitBreaker(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// QueueStatus operation middleware
+func (siw *ServerInterfaceWrapper) QueueStatus(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.QueueStatus(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// VersionInfo operation middleware
+func (siw *ServerInterfaceWrapper) VersionInfo(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.VersionInfo(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 type UnescapedCookieParamError struct {
 	ParamName string
 	Err       error
@@ -201,9 +904,42 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 		ErrorHandlerFunc:   options.ErrorHandlerFunc,
 	}
 
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/admin/collector", wrapper.CollectorStatus)
+	})
+	r.Group(func(r chi.Router) {
+		r.Put(options.BaseURL+"/admin/collector/{source}/interval", wrapper.CollectorSetInterval)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/admin/collector/{source}/pause", wrapper.CollectorPause)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/admin/collector/{source}/resume", wrapper.CollectorResume)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/admin/collector/{source}/trigger", wrapper.CollectorTrigger)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/data-point", wrapper.DataPointQuery)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/data-point", wrapper.DataPointPush)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/data-point/aggregate", wrapper.DataPointAggregate)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/data-point/discarded", wrapper.DataPointQueryDiscarded)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/data-server/circuit-breaker", wrapper.DataServerCircuitBreaker)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/queue", wrapper.QueueStatus)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/version", wrapper.VersionInfo)
+	})
 
 	return r
 }
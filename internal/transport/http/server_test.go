@@ -0,0 +1,579 @@
+package http
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"oc-data-be-challenge/internal/client"
+	"oc-data-be-challenge/internal/collector"
+	"oc-data-be-challenge/internal/data/dto"
+	"oc-data-be-challenge/internal/data/iter"
+	"oc-data-be-challenge/internal/data/repository"
+	"oc-data-be-challenge/internal/data/wal"
+	"oc-data-be-challenge/internal/discard"
+	"oc-data-be-challenge/internal/usecase"
+	"oc-data-be-challenge/internal/utils/version"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestHandler returns the HTTP handler backed by an in-memory store.
+func newTestHandler(t *testing.T) (http.Handler, *repository.MemoryDataPoint) {
+	repo := repository.NewMemoryDataPoint()
+	uc := usecase.NewDataPointUseCase(repo, nil, nil, nil, nil, "test")
+	return Handler(NewChiServer(uc, nil)), repo
+}
+
+// TestChiServer_DataPointQuery tests that data points are returned with their tags, received_at and sub-second time.
+func TestChiServer_DataPointQuery(t *testing.T) {
+	handler, repo := newTestHandler(t)
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, repo.Write(context.Background(), dto.DataPoint{
+		Time: base, Value: 1.5, Tags: []string{"a", "b"}, ReceivedAt: base.Add(1500 * time.Millisecond),
+	}))
+	require.NoError(t, repo.Write(context.Background(), dto.DataPoint{
+		Time: base.Add(time.Minute + 123456789), Value: 2.5, ReceivedAt: base.Add(time.Minute),
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data-point", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got []DataPointModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, []DataPointModel{
+		{Time: "2025-01-01T00:01:00.123456789Z", Value: 2.5, Tags: []string{}, ReceivedAt: "2025-01-01T00:01:00Z"},
+		{Time: "2025-01-01T00:00:00Z", Value: 1.5, Tags: []string{"a", "b"}, ReceivedAt: "2025-01-01T00:00:01.5Z"},
+	}, got)
+}
+
+// TestChiServer_DataPointQueryTags tests that the tag query parameters filter the returned data points.
+func TestChiServer_DataPointQueryTags(t *testing.T) {
+	handler, repo := newTestHandler(t)
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, tags := range [][]string{{"temp", "north"}, {"temp", "south"}, {"humidity"}} {
+		require.NoError(t, repo.Write(context.Background(), dto.DataPoint{
+			Time: base.Add(time.Duration(i) * time.Minute), Value: float64(i), Tags: tags, ReceivedAt: base,
+		}))
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data-point?tag=temp&tag_none=north&tag_any=south&tag_any=humidity", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got []DataPointModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, []string{"temp", "south"}, got[0].Tags)
+}
+
+// newDataServer returns a data server always answering with a data point of value and tags.
+func newDataServer(t *testing.T, value float32, tags []string) *httptest.Server {
+	valueBytes, err := json.Marshal(binary.LittleEndian.AppendUint32(nil, math.Float32bits(value)))
+	require.NoError(t, err)
+	tagsJSON, err := json.Marshal(tags)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"time":%d,"value":%s,"tags":%s}`, time.Now().Unix(), valueBytes, tagsJSON)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestChiServer_DataPointQuerySource tests that collected data points are labelled with their source, discarded by
+// the rules of their source, and filtered by the source query parameter.
+func TestChiServer_DataPointQuerySource(t *testing.T) {
+	denySystem, err := discard.NewEngine([]discard.RuleConfig{{Name: "deny", Type: discard.TypeTagDeny, Tags: []string{"system"}}})
+	require.NoError(t, err)
+	sources := []usecase.DataSource{
+		{Name: "north", Client: client.NewDataServerClient("north", newDataServer(t, 1, []string{"system"}).URL, nil, client.RetryPolicy{}, nil, client.DecodeOptions{})},
+		{Name: "south", Client: client.NewDataServerClient("south", newDataServer(t, 2, []string{"system"}).URL, nil, client.RetryPolicy{}, nil, client.DecodeOptions{}), DiscardRules: denySystem},
+		{Name: "east", Client: client.NewDataServerClient("east", newDataServer(t, 3, nil).URL, nil, client.RetryPolicy{}, nil, client.DecodeOptions{})},
+	}
+
+	repo := repository.NewMemoryDataPoint()
+	uc := usecase.NewDataPointUseCase(repo, sources, nil, nil, nil, "test")
+	for _, source := range sources {
+		require.NoError(t, uc.Collect(context.Background(), source))
+	}
+	handler := Handler(NewChiServer(uc, nil))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data-point?source=north&source=south", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got []DataPointModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, float64(1), got[0].Value)
+	require.NotNil(t, got[0].Source)
+	assert.Equal(t, "north", *got[0].Source)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data-point/discarded", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var discarded []DiscardedDataPointModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &discarded))
+	require.Len(t, discarded, 1)
+	require.NotNil(t, discarded[0].Source)
+	assert.Equal(t, "south", *discarded[0].Source)
+}
+
+// TestChiServer_DataPointQueryPagination tests paging through data points with the X-Next-Cursor header.
+func TestChiServer_DataPointQueryPagination(t *testing.T) {
+	handler, repo := newTestHandler(t)
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := range 5 {
+		require.NoError(t, repo.Write(context.Background(), dto.DataPoint{
+			Time: base.Add(time.Duration(i) * time.Minute), Value: float64(i), ReceivedAt: base,
+		}))
+	}
+
+	var values []float64
+	var pages int
+	target := "/data-point?limit=2"
+	for target != "" {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var got []DataPointModel
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		for _, dp := range got {
+			values = append(values, dp.Value)
+		}
+		pages++
+
+		target = ""
+		if cursor := rec.Header().Get("X-Next-Cursor"); cursor != "" {
+			target = "/data-point?limit=2&cursor=" + url.QueryEscape(cursor)
+		}
+	}
+
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []float64{4, 3, 2, 1, 0}, values)
+}
+
+// TestChiServer_DataPointQueryUnpaged tests that every matching data point is returned at once when neither limit nor
+// cursor is given.
+func TestChiServer_DataPointQueryUnpaged(t *testing.T) {
+	handler, repo := newTestHandler(t)
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := range defaultQueryLimit + 1 {
+		require.NoError(t, repo.Write(context.Background(), dto.DataPoint{
+			Time: base.Add(time.Duration(i) * time.Second), Value: float64(i), ReceivedAt: base,
+		}))
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data-point", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("X-Next-Cursor"))
+
+	var got []DataPointModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Len(t, got, defaultQueryLimit+1)
+}
+
+// failingIter is an iterator over points that fails once they are all returned.
+type failingIter struct {
+	*iter.SliceDataPointIter
+	err error
+}
+
+func (fi failingIter) Err() error {
+	return fi.err
+}
+
+// failingQueryStore is a store whose queries fail after the data points of the wrapped store are returned.
+type failingQueryStore struct {
+	*repository.MemoryDataPoint
+	err error
+}
+
+func (fs failingQueryStore) Query(ctx context.Context, filter dto.DataPointFilter) (iter.DataPointIterator, error) {
+	points, err := fs.MemoryDataPoint.Query(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var page []dto.DataPoint
+	for points.Next() {
+		point, _ := points.Value()
+		page = append(page, point)
+	}
+	return failingIter{SliceDataPointIter: iter.NewSliceDataPointIter(page), err: fs.err}, nil
+}
+
+// TestChiServer_DataPointQueryFailure tests that a query failing while its data points are read is answered with an
+// error rather than a page that would be taken for the last one.
+func TestChiServer_DataPointQueryFailure(t *testing.T) {
+	repo := repository.NewMemoryDataPoint()
+	store := failingQueryStore{MemoryDataPoint: repo, err: errors.New("stream reset")}
+	handler := Handler(NewChiServer(usecase.NewDataPointUseCase(store, nil, nil, nil, nil, "test"), nil))
+	require.NoError(t, repo.Write(context.Background(), dto.DataPoint{Time: time.Now(), Value: 1.5}))
+
+	for _, target := range []string{"/data-point", "/data-point?limit=2"} {
+		t.Run(target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			assert.Equal(t, http.StatusInternalServerError, rec.Code)
+			assert.Contains(t, rec.Body.String(), "stream reset")
+			assert.Empty(t, rec.Header().Get("X-Next-Cursor"))
+		})
+	}
+}
+
+// TestChiServer_DataPointQueryInvalidPagination tests that invalid limits and cursors are rejected.
+func TestChiServer_DataPointQueryInvalidPagination(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	for _, target := range []string{
+		"/data-point?limit=0",
+		"/data-point?limit=10001",
+		"/data-point?limit=many",
+		"/data-point?cursor=not-a-cursor",
+	} {
+		t.Run(target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+// TestChiServer_DataPointAggregate tests aggregating data points in windows.
+func TestChiServer_DataPointAggregate(t *testing.T) {
+	handler, repo := newTestHandler(t)
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := range 4 {
+		require.NoError(t, repo.Write(context.Background(), dto.DataPoint{
+			Time: base.Add(time.Duration(i) * 30 * time.Second), Value: float64(i), ReceivedAt: base,
+		}))
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data-point/aggregate?window=1m&fn=max", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got []AggregatePointModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, []AggregatePointModel{
+		{Time: "2025-01-01T00:01:00Z", Value: 3},
+		{Time: "2025-01-01T00:00:00Z", Value: 1},
+	}, got)
+}
+
+// TestChiServer_DataPointAggregateInvalid tests that invalid windows and functions are rejected, as well as time ranges
+// covering too many windows.
+func TestChiServer_DataPointAggregateInvalid(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	for _, target := range []string{
+		"/data-point/aggregate?fn=mean",
+		"/data-point/aggregate?window=1m",
+		"/data-point/aggregate?window=1d&fn=mean",
+		"/data-point/aggregate?window=1ms&fn=mean",
+		"/data-point/aggregate?window=1m&fn=median",
+		"/data-point/aggregate?window=1m&fn=p101",
+		"/data-point/aggregate?window=1s&fn=mean",
+		"/data-point/aggregate?window=1s&fn=mean&start=2025-01-01T00:00:00Z&until=2025-01-02T00:00:00Z",
+	} {
+		t.Run(target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+// TestChiServer_DataPointQueryInvalidTime tests that an invalid time range is rejected.
+func TestChiServer_DataPointQueryInvalidTime(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data-point?start=yesterday", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// TestChiServer_DataPointQueryDiscarded tests filtering discarded data points by reason.
+func TestChiServer_DataPointQueryDiscarded(t *testing.T) {
+	handler, repo := newTestHandler(t)
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, repo.WriteDiscard(context.Background(), dto.DataPoint{
+		Time: base, Value: 1, Tags: []string{"system"}, ReceivedAt: base,
+		DiscardRule: "denied_tags", DiscardReason: "tag_deny", DiscardDetail: "system", DiscardedBy: "test",
+	}))
+	require.NoError(t, repo.WriteDiscard(context.Background(), dto.DataPoint{
+		Time: base.Add(time.Minute), Value: 2, ReceivedAt: base,
+		DiscardRule: "max_age", DiscardReason: "max_age", DiscardDetail: "1h0m0s", DiscardedBy: "test",
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data-point/discarded?reason=tag_deny", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got []DiscardedDataPointModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, []string{"system"}, got[0].Tags)
+	require.NotNil(t, got[0].Detail)
+	assert.Equal(t, "system", *got[0].Detail)
+}
+
+// TestChiServer_DataPointPush tests that pushed data points are validated, go through the push discard rules and are
+// labelled with the push data source whatever the producer asks for.
+func TestChiServer_DataPointPush(t *testing.T) {
+	repo := repository.NewMemoryDataPoint()
+	denySystem, err := discard.NewEngine([]discard.RuleConfig{{Name: "deny", Type: discard.TypeTagDeny, Tags: []string{"system"}}})
+	require.NoError(t, err)
+	handler := Handler(NewChiServer(usecase.NewDataPointUseCase(repo, nil, denySystem, nil, nil, "test"), nil))
+
+	valueBytes, err := json.Marshal(binary.LittleEndian.AppendUint32(nil, math.Float32bits(1.5)))
+	require.NoError(t, err)
+	now := time.Now().Unix()
+	body := fmt.Sprintf(`{"time":%d,"value":%s,"tags":["temp"]}
+{"time":%d,"value":%s,"tags":["system"]}
+{"time":%d,"tags":[]}
+`, now, valueBytes, now+1, valueBytes, now+2)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/data-point?source=north", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var result PushResultModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, PushResultModel{Accepted: 1, Discarded: 1, Invalid: 1}, result)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data-point", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got []DataPointModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, []string{"temp"}, got[0].Tags)
+	assert.Equal(t, 1.5, got[0].Value)
+	require.NotNil(t, got[0].Source)
+	assert.Equal(t, usecase.DefaultPushSource, *got[0].Source)
+}
+
+// TestChiServer_DataPointPushTime tests that pushed times are accepted both as unix timestamps and as RFC3339 strings,
+// as described by PushDataPointModel.
+func TestChiServer_DataPointPushTime(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	value := binary.LittleEndian.AppendUint32(nil, math.Float32bits(1.5))
+	at := time.Date(2025, 1, 1, 0, 0, 0, 123456789, time.UTC)
+
+	unix := PushDataPointModel{Value: value, Tags: []string{}}
+	require.NoError(t, unix.Time.FromPushDataPointModelTime0(at.Add(-time.Minute).UnixMilli()))
+	rfc3339 := PushDataPointModel{Value: value, Tags: []string{}}
+	require.NoError(t, rfc3339.Time.FromPushDataPointModelTime1(at))
+	body, err := json.Marshal([]PushDataPointModel{unix, rfc3339})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/data-point", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data-point", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got []DataPointModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 2)
+	assert.Equal(t, "2025-01-01T00:00:00.123456789Z", got[0].Time)
+	assert.Equal(t, "2024-12-31T23:59:00.123Z", got[1].Time)
+}
+
+// TestChiServer_DataPointPushMalformed tests that a body failing to decode is rejected.
+func TestChiServer_DataPointPushMalformed(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/data-point", strings.NewReader(`[{"time":`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// TestChiServer_QueueStatus tests that the write-ahead log depth is reported until the queued data points are drained.
+func TestChiServer_QueueStatus(t *testing.T) {
+	repo := repository.NewMemoryDataPoint()
+	queue, err := wal.Open(t.TempDir(), wal.Options{NoSync: true})
+	require.NoError(t, err)
+	defer queue.Close()
+
+	uc := usecase.NewDataPointUseCase(repo, nil, nil, queue, nil, "test")
+	handler := Handler(NewChiServer(uc, nil))
+
+	appendedAt := time.Now().Add(-time.Minute)
+	require.NoError(t, queue.Append(wal.Entry{Point: dto.DataPoint{Time: appendedAt, Value: 1}, AppendedAt: appendedAt}))
+	require.NoError(t, queue.Append(wal.Entry{Point: dto.DataPoint{Time: appendedAt, Value: 2}, Discard: true}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/queue", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got QueueStatusModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.True(t, got.Enabled)
+	assert.Equal(t, int64(2), got.Depth)
+	require.NotNil(t, got.OldestAgeMs)
+	assert.GreaterOrEqual(t, *got.OldestAgeMs, time.Minute.Milliseconds())
+	oldestAppendedAt, err := time.Parse(time.RFC3339, *got.OldestAppendedAt)
+	require.NoError(t, err)
+	assert.WithinDuration(t, appendedAt, oldestAppendedAt, time.Second)
+
+	require.NoError(t, uc.Drain(context.Background()))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/queue", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"enabled":true,"depth":0}`, rec.Body.String())
+
+	points, err := repo.Query(context.Background(), dto.DataPointFilter{})
+	require.NoError(t, err)
+	require.True(t, points.Next())
+	point, err := points.Value()
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), point.Value)
+	discarded, err := repo.QueryDiscarded(context.Background(), dto.DataPointFilter{}, "")
+	require.NoError(t, err)
+	require.True(t, discarded.Next())
+	point, err = discarded.Value()
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), point.Value)
+}
+
+// TestChiServer_DataServerCircuitBreaker tests that the circuit breaker state of every source is reported.
+func TestChiServer_DataServerCircuitBreaker(t *testing.T) {
+	breaker := client.NewCircuitBreaker("north", client.BreakerOptions{FailureThreshold: 1, CoolDown: time.Minute})
+	sources := []usecase.DataSource{
+		{Name: "north", Client: client.NewDataServerClient("north", "http://127.0.0.1:0", nil, client.RetryPolicy{}, breaker, client.DecodeOptions{})},
+		{Name: "south", Client: client.NewDataServerClient("south", "http://127.0.0.1:0", nil, client.RetryPolicy{}, nil, client.DecodeOptions{})},
+	}
+	handler := Handler(NewChiServer(usecase.NewDataPointUseCase(repository.NewMemoryDataPoint(), sources, nil, nil, nil, "test"), nil))
+	breaker.Failure()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data-server/circuit-breaker", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got []CircuitBreakerStatusModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 2)
+	assert.Equal(t, "north", got[0].Source)
+	assert.True(t, got[0].Enabled)
+	assert.Equal(t, "open", got[0].State)
+	assert.Equal(t, int32(1), got[0].ConsecutiveFailures)
+	require.NotNil(t, got[0].ChangedAt)
+	require.NotNil(t, got[0].RetryAt)
+	assert.Equal(t, CircuitBreakerStatusModel{Source: "south", State: "closed"}, got[1])
+}
+
+// TestChiServer_VersionInfo tests that the build information is returned.
+func TestChiServer_VersionInfo(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got VersionModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	info := version.BuildInfo{}.Info()
+	assert.Equal(t, info.Revision, got.Revision)
+	assert.Equal(t, info.BuildHost, got.BuildMachine)
+	assert.Equal(t, runtime.Version(), got.GoVersion)
+}
+
+// TestVersionHeader tests that every response carries the version of the build.
+func TestVersionHeader(t *testing.T) {
+	handler := VersionHeader(http.NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, version.BuildInfo{}.Info().Version(), rec.Header().Get("X-App-Version"))
+}
+
+// TestChiServer_Collector tests that the collector of a data source is paused, resumed, triggered and rescheduled
+// through the admin endpoints, which respond with its status.
+func TestChiServer_Collector(t *testing.T) {
+	source := usecase.DataSource{
+		Name:   "north",
+		Client: client.NewDataServerClient("north", newDataServer(t, 1.5, []string{"tag1"}).URL, nil, client.RetryPolicy{}, nil, client.DecodeOptions{}),
+	}
+	repo := repository.NewMemoryDataPoint()
+	uc := usecase.NewDataPointUseCase(repo, []usecase.DataSource{source}, nil, nil, nil, "test")
+	trigger := collector.NewDataServerCollector(uc, source, time.Hour)
+	go trigger.Start()
+	defer trigger.Stop()
+	require.Eventually(t, func() bool { return trigger.Status().Runs == 1 }, time.Second, 10*time.Millisecond)
+	handler := Handler(NewChiServer(uc, map[string]*collector.PeriodicTrigger{"north": trigger}))
+
+	do := func(method string, target string, body string) (*httptest.ResponseRecorder, CollectorStatusModel) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		var got CollectorStatusModel
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		}
+		return rec, got
+	}
+
+	rec, got := do(http.MethodPost, "/admin/collector/north/pause", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "paused", got.State)
+
+	rec, got = do(http.MethodPost, "/admin/collector/north/trigger", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "paused", got.State)
+	assert.Equal(t, int64(2), got.RunCount)
+	assert.NotNil(t, got.LastRunAt)
+	assert.Nil(t, got.LastError)
+
+	rec, got = do(http.MethodPut, "/admin/collector/north/interval", `{"interval_ms":5000}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int64(5000), got.IntervalMs)
+
+	rec, got = do(http.MethodPost, "/admin/collector/north/resume", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "running", got.State)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/collector", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var all []CollectorStatusModel
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &all))
+	require.Len(t, all, 1)
+	assert.Equal(t, "north", all[0].Source)
+
+	rec, _ = do(http.MethodPost, "/admin/collector/south/pause", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec, _ = do(http.MethodPut, "/admin/collector/north/interval", `{"interval_ms":0}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	trigger.Stop()
+	rec, _ = do(http.MethodPost, "/admin/collector/north/trigger", "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
@@ -2,23 +2,75 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	goiter "iter"
 	"log/slog"
 	"oc-data-be-challenge/internal/client"
 	"oc-data-be-challenge/internal/data/dto"
 	"oc-data-be-challenge/internal/data/iter"
 	"oc-data-be-challenge/internal/data/repository"
+	"oc-data-be-challenge/internal/data/wal"
+	"oc-data-be-challenge/internal/dedup"
+	"oc-data-be-challenge/internal/discard"
+	"oc-data-be-challenge/internal/metrics"
+	"sync"
 	"time"
 )
 
+// drainBatchSize is the largest number of write-ahead log entries written to the repository at once.
+const drainBatchSize = 500
+
+// DefaultPushSource labels every pushed data point, producers cannot name the data source of the data points they push.
+const DefaultPushSource = "push"
+
+// DataSource is an upstream data server collected on its own schedule.
+type DataSource struct {
+	// Name labels the data points collected from the source.
+	Name   string
+	Client *client.DataServerClient
+	// DiscardRules decide which data points collected from the source are discarded, nil keeps them all.
+	DiscardRules *discard.Engine
+	// PollInterval is how often the source is collected.
+	PollInterval time.Duration
+	// Batch is true when the source returns a batch of data points per request, see client.DataServerClient.DataPoints.
+	Batch bool
+}
+
 type DataPointUseCase struct {
-	repo             *repository.DataPoint
-	dataServerClient *client.DataServerClient
-	logger           *slog.Logger
+	repo    repository.DataPointStore
+	sources []DataSource
+	// pushRules decide which pushed data points are discarded, nil keeps them all.
+	pushRules *discard.Engine
+	// queue is the write-ahead log collected data points go through, nil to write them to repo directly.
+	queue *wal.Log
+	// deduplicator detects data points collected more than once, nil to store them all.
+	deduplicator *dedup.Deduplicator
+	// instance identifies this collector on the data points it discards.
+	instance string
+	// lastCollected is the time of the last successful Collect of every data source, by name.
+	lastCollected map[string]time.Time
+	collectedMu   sync.RWMutex
+	logger        *slog.Logger
+}
+
+func NewDataPointUseCase(repo repository.DataPointStore, sources []DataSource, pushRules *discard.Engine, queue *wal.Log, deduplicator *dedup.Deduplicator, instance string) *DataPointUseCase {
+	return &DataPointUseCase{
+		repo:          repo,
+		sources:       sources,
+		pushRules:     pushRules,
+		queue:         queue,
+		deduplicator:  deduplicator,
+		instance:      instance,
+		lastCollected: make(map[string]time.Time, len(sources)),
+		logger:        slog.With("component", "DataPointUseCase"),
+	}
 }
 
-func NewDataPointUseCase(repo *repository.DataPoint, dataServerClient *client.DataServerClient) *DataPointUseCase {
-	return &DataPointUseCase{repo: repo, dataServerClient: dataServerClient, logger: slog.With("component", "DataPointUseCase")}
+// Sources returns the data sources the use case collects from.
+func (dpuc *DataPointUseCase) Sources() []DataSource {
+	return dpuc.sources
 }
 
 func (dpuc *DataPointUseCase) Write(ctx context.Context, point dto.DataPoint) error {
@@ -29,57 +81,344 @@ func (dpuc *DataPointUseCase) WriteDiscard(ctx context.Context, point dto.DataPo
 	return dpuc.repo.WriteDiscard(ctx, point)
 }
 
-func (dpuc *DataPointUseCase) Read(ctx context.Context) (client.DataPoint, error) {
-	dp, err := dpuc.dataServerClient.DataPoint(ctx)
+func (dpuc *DataPointUseCase) Read(ctx context.Context, source DataSource) (client.DataPoint, error) {
+	dp, err := source.Client.DataPoint(ctx)
 	if err != nil {
-		return client.DataPoint{}, fmt.Errorf("failed to get datapoint from data server: %w", err)
+		return client.DataPoint{}, fmt.Errorf("failed to get datapoint from data server %q: %w", source.Name, err)
 	}
 
 	return dp, nil
 }
 
-func (dpuc *DataPointUseCase) Collect(ctx context.Context) error {
-	dp, err := dpuc.Read(ctx)
+// Collect reads a data point from source and stores it, labelled with the source name, unless the discard rules of
+// the source drop it. A data point already collected from source is skipped or stored as a duplicate. A batch source
+// is read in full and its data points are stored at once, see collectBatch.
+func (dpuc *DataPointUseCase) Collect(ctx context.Context, source DataSource) error {
+	collect := dpuc.collectOne
+	if source.Batch {
+		collect = dpuc.collectBatch
+	}
+	if err := collect(ctx, source); err != nil {
+		return err
+	}
+
+	dpuc.collectedMu.Lock()
+	defer dpuc.collectedMu.Unlock()
+	dpuc.lastCollected[source.Name] = time.Now()
+	return nil
+}
+
+// LastCollected returns the time of the last successful Collect of the data source named source, false when it has
+// not been collected yet.
+func (dpuc *DataPointUseCase) LastCollected(source string) (time.Time, bool) {
+	dpuc.collectedMu.RLock()
+	defer dpuc.collectedMu.RUnlock()
+	at, ok := dpuc.lastCollected[source]
+	return at, ok
+}
+
+// collectOne reads a single data point from source and stores it.
+func (dpuc *DataPointUseCase) collectOne(ctx context.Context, source DataSource) error {
+	dp, err := dpuc.Read(ctx, source)
 	if err != nil {
+		if errors.Is(err, client.ErrInvalidDataPoint) {
+			metrics.DataPoints.WithLabelValues(source.Name, metrics.OutcomeInvalid).Inc()
+		}
 		return fmt.Errorf("failed to read datapoint: %w", err)
 	}
 
-	if dp.Time.Value.Before(time.Now().Add(-1 * time.Hour)) {
-		dpuc.logger.InfoContext(ctx, "Dropping datapoint", "reason", "timestamp too old", "t", dp.Time.Value)
-		return dpuc.repo.WriteDiscard(ctx, dto.DataPoint{
-			Time:       dp.Time.Value,
-			Value:      dp.Value.Value,
-			Tags:       dp.Tags.Value,
-			ReceivedAt: time.Now(),
-		})
-	}
-
-	for _, value := range dp.Tags.Value {
-		// drop data points with tag "system" or "suspect"
-		if value == "system" || value == "suspect" {
-			dpuc.logger.InfoContext(ctx, "Dropping datapoint", "tag", value, "t", dp.Time.Value)
-			return dpuc.repo.WriteDiscard(ctx, dto.DataPoint{
-				Time:       dp.Time.Value,
-				Value:      dp.Value.Value,
-				Tags:       dp.Tags.Value,
-				ReceivedAt: time.Now(),
-			})
+	entry, ok := dpuc.classify(ctx, newPoint(dp, source.Name, time.Now()), source.DiscardRules)
+	if !ok {
+		return nil
+	}
+	return dpuc.store(ctx, entry)
+}
+
+// collectBatch reads a batch of data points from source and stores them at once, see ingest.
+func (dpuc *DataPointUseCase) collectBatch(ctx context.Context, source DataSource) error {
+	if _, err := dpuc.ingest(ctx, source.Name, source.DiscardRules, source.Client.DataPoints(ctx)); err != nil {
+		return fmt.Errorf("failed to collect datapoints from data server %q: %w", source.Name, err)
+	}
+	return nil
+}
+
+// Push stores the data points pushed by a producer, read from body in the wire format of the data server, as a
+// single data point, a JSON array or newline delimited JSON. They are labelled with DefaultPushSource and go through
+// the same validation and deduplication as collected data points, and through the push rules. Errors of body wrap
+// client.ErrMalformedBody.
+func (dpuc *DataPointUseCase) Push(ctx context.Context, body io.Reader) (dto.IngestResult, error) {
+	result, err := dpuc.ingest(ctx, DefaultPushSource, dpuc.pushRules, client.DecodeDataPoints(body, client.DecodeOptions{}))
+	if err != nil {
+		return result, fmt.Errorf("failed to push datapoints: %w", err)
+	}
+	return result, nil
+}
+
+// ingest stores the data points to keep with a single write-ahead log append, or a single repository write per table
+// without one. Invalid data points are logged and skipped. When points ends early on an error the data points read
+// until then are still stored, they would be taken for duplicates if they were read again.
+func (dpuc *DataPointUseCase) ingest(ctx context.Context, source string, rules *discard.Engine, points goiter.Seq2[client.DataPoint, error]) (dto.IngestResult, error) {
+	var result dto.IngestResult
+	receivedAt := time.Now()
+	var entries []wal.Entry
+	var readErr error
+	for dp, err := range points {
+		if errors.Is(err, client.ErrInvalidDataPoint) {
+			dpuc.logger.WarnContext(ctx, "Skipping invalid datapoint", "source", source, "error", err)
+			metrics.DataPoints.WithLabelValues(source, metrics.OutcomeInvalid).Inc()
+			result.Invalid++
+			continue
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+
+		entry, ok := dpuc.classify(ctx, newPoint(dp, source, receivedAt), rules)
+		switch {
+		case !ok || entry.Duplicate:
+			result.Duplicates++
+		case entry.Discard:
+			result.Discarded++
+		default:
+			result.Accepted++
+		}
+		if ok {
+			entries = append(entries, entry)
 		}
 	}
 
-	return dpuc.repo.Write(ctx, dto.DataPoint{
+	if err := dpuc.storeBatch(ctx, entries); err != nil {
+		return result, errors.Join(readErr, err)
+	}
+	return result, readErr
+}
+
+// newPoint converts a data point read from the data server source.
+func newPoint(dp client.DataPoint, source string, receivedAt time.Time) dto.DataPoint {
+	return dto.DataPoint{
 		Time:       dp.Time.Value,
 		Value:      dp.Value.Value,
 		Tags:       dp.Tags.Value,
-		ReceivedAt: time.Now(),
-	})
+		Source:     source,
+		ReceivedAt: receivedAt,
+	}
+}
+
+// classify returns the write-ahead log entry of point: a duplicate, discarded by rules or accepted, and counts it in
+// the data point metrics. It returns false when point is a duplicate to skip.
+func (dpuc *DataPointUseCase) classify(ctx context.Context, point dto.DataPoint, rules *discard.Engine) (wal.Entry, bool) {
+	if dpuc.deduplicator != nil && dpuc.deduplicator.Duplicate(point) {
+		metrics.DataPoints.WithLabelValues(point.Source, metrics.OutcomeDuplicate).Inc()
+		dpuc.logger.DebugContext(ctx, "Duplicate datapoint", "source", point.Source, "t", point.Time, "action", dpuc.deduplicator.Action(), "duplicates", dpuc.deduplicator.Duplicates())
+		if dpuc.deduplicator.Action() != dedup.ActionStore {
+			return wal.Entry{}, false
+		}
+		return wal.Entry{Duplicate: true, Point: point}, true
+	}
+
+	if rules == nil {
+		metrics.DataPoints.WithLabelValues(point.Source, metrics.OutcomeAccepted).Inc()
+		return wal.Entry{Point: point}, true
+	}
+
+	if verdict, ok := rules.Evaluate(point); ok {
+		metrics.DataPoints.WithLabelValues(point.Source, metrics.OutcomeDiscarded).Inc()
+		metrics.DataPointsDiscarded.WithLabelValues(point.Source, verdict.Reason).Inc()
+		dpuc.logger.InfoContext(ctx, "Dropping datapoint", "source", point.Source, "rule", verdict.Rule, "reason", verdict.Reason, "detail", verdict.Detail, "t", point.Time)
+		point.DiscardRule = verdict.Rule
+		point.DiscardReason = verdict.Reason
+		point.DiscardDetail = verdict.Detail
+		point.DiscardedBy = dpuc.instance
+		return wal.Entry{Discard: true, Point: point}, true
+	}
+
+	metrics.DataPoints.WithLabelValues(point.Source, metrics.OutcomeAccepted).Inc()
+	return wal.Entry{Point: point}, true
+}
+
+// forget makes the deduplicator forget the data points of entries that could not be stored. Duplicates were remembered
+// before entries, they are not forgotten.
+func (dpuc *DataPointUseCase) forget(entries ...wal.Entry) {
+	if dpuc.deduplicator == nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.Duplicate {
+			dpuc.deduplicator.Forget(entry.Point)
+		}
+	}
+}
+
+// store appends entry to the write-ahead log when there is one, its point is then written to the repository by Drain.
+// Without a write-ahead log the point is written to the repository directly. A point that cannot be stored is
+// forgotten by the deduplicator, so that it is stored when it is collected again.
+func (dpuc *DataPointUseCase) store(ctx context.Context, entry wal.Entry) error {
+	if dpuc.queue != nil {
+		if err := dpuc.queue.Append(entry); err != nil {
+			dpuc.forget(entry)
+			return fmt.Errorf("failed to queue datapoint: %w", err)
+		}
+		return nil
+	}
+
+	if err := dpuc.write(ctx, entry, entry.Point); err != nil {
+		dpuc.forget(entry)
+		return err
+	}
+	return nil
 }
 
-func (dpuc *DataPointUseCase) Query(ctx context.Context, start, until *time.Time) (*iter.DataPointIter, error) {
-	resultIter, err := dpuc.repo.Query(ctx, start, until)
+// write writes points to the repository table selected by the flags of entry: duplicate, discarded or accepted.
+func (dpuc *DataPointUseCase) write(ctx context.Context, entry wal.Entry, points ...dto.DataPoint) error {
+	switch {
+	case entry.Duplicate:
+		return dpuc.repo.WriteDuplicate(ctx, points...)
+	case entry.Discard:
+		return dpuc.repo.WriteDiscard(ctx, points...)
+	default:
+		return dpuc.repo.Write(ctx, points...)
+	}
+}
+
+// storeBatch appends entries to the write-ahead log at once when there is one. Without a write-ahead log their points
+// are written to the repository directly, one write per run of entries going to the same table. The points that cannot
+// be stored are forgotten by the deduplicator, like in store.
+func (dpuc *DataPointUseCase) storeBatch(ctx context.Context, entries []wal.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if dpuc.queue != nil {
+		if err := dpuc.queue.Append(entries...); err != nil {
+			dpuc.forget(entries...)
+			return fmt.Errorf("failed to queue %d datapoints: %w", len(entries), err)
+		}
+		return nil
+	}
+
+	for len(entries) > 0 {
+		run := runLength(entries)
+		if err := dpuc.write(ctx, entries[0], points(entries[:run])...); err != nil {
+			// the runs written before are stored, they are still remembered
+			dpuc.forget(entries...)
+			return fmt.Errorf("failed to write %d datapoints: %w", run, err)
+		}
+		entries = entries[run:]
+	}
+	return nil
+}
+
+// runLength returns the number of leading entries going to the same repository table as the first one.
+func runLength(entries []wal.Entry) int {
+	run := 1
+	for run < len(entries) && entries[run].Discard == entries[0].Discard && entries[run].Duplicate == entries[0].Duplicate {
+		run++
+	}
+	return run
+}
+
+// points returns the data points of entries.
+func points(entries []wal.Entry) []dto.DataPoint {
+	points := make([]dto.DataPoint, 0, len(entries))
+	for _, entry := range entries {
+		points = append(points, entry.Point)
+	}
+	return points
+}
+
+// Drain writes the data points waiting in the write-ahead log to the repository, in batches and in the order they
+// were collected. It returns once the log is empty or a write fails, the failed batch stays in the log and is written
+// again by the next call.
+func (dpuc *DataPointUseCase) Drain(ctx context.Context) error {
+	if dpuc.queue == nil {
+		return nil
+	}
+
+	for {
+		batch, err := dpuc.queue.Read(drainBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to read write-ahead log: %w", err)
+		}
+		if len(batch.Entries) == 0 {
+			return nil
+		}
+
+		// accepted, discarded and duplicate data points go to different tables, every run is written and committed on its
+		// own so a failure never leaves a run partially committed
+		for len(batch.Entries) > 0 {
+			run := runLength(batch.Entries)
+			if err := dpuc.write(ctx, batch.Entries[0], points(batch.Entries[:run])...); err != nil {
+				return fmt.Errorf("failed to write %d queued datapoints: %w", run, err)
+			}
+
+			if err := dpuc.queue.Commit(batch.Head(run)); err != nil {
+				return fmt.Errorf("failed to commit write-ahead log: %w", err)
+			}
+			batch = batch.Tail(run)
+		}
+	}
+}
+
+// SourceBreakerStatus is the state of the circuit breaker of a data source.
+type SourceBreakerStatus struct {
+	Source string
+	// Enabled is false when the source has no circuit breaker.
+	Enabled bool
+	client.BreakerStatus
+}
+
+// CircuitBreakerStatus returns the state of the circuit breaker of every data source, in configuration order.
+func (dpuc *DataPointUseCase) CircuitBreakerStatus() []SourceBreakerStatus {
+	statuses := make([]SourceBreakerStatus, 0, len(dpuc.sources))
+	for _, source := range dpuc.sources {
+		status := SourceBreakerStatus{Source: source.Name, BreakerStatus: client.BreakerStatus{State: client.BreakerClosed}}
+		if breaker := source.Client.Breaker(); breaker != nil {
+			status.Enabled = true
+			status.BreakerStatus = breaker.Status()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// QueueStatus returns the number of data points waiting in the write-ahead log and the age of the oldest one.
+func (dpuc *DataPointUseCase) QueueStatus() (dto.QueueStatus, error) {
+	if dpuc.queue == nil {
+		return dto.QueueStatus{}, nil
+	}
+
+	stats, err := dpuc.queue.Stats()
+	if err != nil {
+		return dto.QueueStatus{}, fmt.Errorf("failed to read write-ahead log stats: %w", err)
+	}
+
+	return dto.QueueStatus{Enabled: true, Depth: stats.Depth, OldestAppendedAt: stats.Oldest}, nil
+}
+
+func (dpuc *DataPointUseCase) Query(ctx context.Context, filter dto.DataPointFilter) (iter.DataPointIterator, error) {
+	resultIter, err := dpuc.repo.Query(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query datapoints: %w", err)
 	}
 
 	return resultIter, nil
 }
+
+func (dpuc *DataPointUseCase) QueryDiscarded(ctx context.Context, filter dto.DataPointFilter, reason string) (iter.DataPointIterator, error) {
+	resultIter, err := dpuc.repo.QueryDiscarded(ctx, filter, reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query discarded datapoints: %w", err)
+	}
+
+	return resultIter, nil
+}
+
+func (dpuc *DataPointUseCase) Aggregate(ctx context.Context, filter dto.DataPointFilter, aggregation dto.Aggregation) ([]dto.AggregatePoint, error) {
+	points, err := dpuc.repo.Aggregate(ctx, filter, aggregation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate datapoints: %w", err)
+	}
+
+	return points, nil
+}
@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"oc-data-be-challenge/internal/data/dto"
+	"oc-data-be-challenge/internal/data/repository"
+	"oc-data-be-challenge/internal/dedup"
+	"oc-data-be-challenge/internal/discard"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingStore is a repository.DataPointStore recording the values of the data points written to every table, its
+// writes fail while err is set.
+type recordingStore struct {
+	repository.DataPointStore
+	err     error
+	written map[string][]float64
+}
+
+func newRecordingStore() *recordingStore {
+	return &recordingStore{written: map[string][]float64{}}
+}
+
+func (s *recordingStore) record(table string, points []dto.DataPoint) error {
+	if s.err != nil {
+		return s.err
+	}
+	for _, point := range points {
+		s.written[table] = append(s.written[table], point.Value)
+	}
+	return nil
+}
+
+func (s *recordingStore) Write(_ context.Context, points ...dto.DataPoint) error {
+	return s.record("accepted", points)
+}
+
+func (s *recordingStore) WriteDiscard(_ context.Context, points ...dto.DataPoint) error {
+	return s.record("discarded", points)
+}
+
+func (s *recordingStore) WriteDuplicate(_ context.Context, points ...dto.DataPoint) error {
+	return s.record("duplicate", points)
+}
+
+// line returns a data point in the wire format of the data server, as a line of a newline delimited JSON body.
+func line(t *testing.T, at time.Time, value float32, tags ...string) string {
+	valueBytes, err := json.Marshal(binary.LittleEndian.AppendUint32(nil, math.Float32bits(value)))
+	require.NoError(t, err)
+	tagsBytes, err := json.Marshal(append([]string{}, tags...))
+	require.NoError(t, err)
+	return fmt.Sprintf(`{"time":%d,"value":%s,"tags":%s}`+"\n", at.Unix(), valueBytes, tagsBytes)
+}
+
+// TestDataPointUseCase_PushDuplicates tests that duplicates are skipped or written to the duplicate table depending on
+// the action of the deduplicator, apart from the accepted and discarded data points.
+func TestDataPointUseCase_PushDuplicates(t *testing.T) {
+	tests := []struct {
+		action dedup.Action
+		want   map[string][]float64
+	}{
+		{
+			action: dedup.ActionSkip,
+			want:   map[string][]float64{"accepted": {1.5}, "discarded": {2.5}},
+		},
+		{
+			action: dedup.ActionStore,
+			want:   map[string][]float64{"accepted": {1.5}, "duplicate": {1.5, 2.5, 1.5}, "discarded": {2.5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.action), func(t *testing.T) {
+			ctx := context.Background()
+			denySystem, err := discard.NewEngine([]discard.RuleConfig{{Name: "deny", Type: discard.TypeTagDeny, Tags: []string{"system"}}})
+			require.NoError(t, err)
+			store := newRecordingStore()
+			uc := NewDataPointUseCase(store, nil, denySystem, nil, dedup.New(10, tt.action), "test")
+
+			now := time.Now()
+			accepted, discarded := line(t, now, 1.5, "temp"), line(t, now.Add(time.Second), 2.5, "system")
+			result, err := uc.Push(ctx, strings.NewReader(accepted+accepted+discarded+discarded))
+			require.NoError(t, err)
+			assert.Equal(t, dto.IngestResult{Accepted: 1, Discarded: 1, Duplicates: 2}, result)
+
+			// the same data point pushed again is a duplicate
+			result, err = uc.Push(ctx, strings.NewReader(accepted))
+			require.NoError(t, err)
+			assert.Equal(t, dto.IngestResult{Duplicates: 1}, result)
+
+			assert.Equal(t, tt.want, store.written)
+		})
+	}
+}
+
+// TestDataPointUseCase_StoreFailure tests that data points that could not be stored are not taken for duplicates when
+// they are received again, while those already stored still are.
+func TestDataPointUseCase_StoreFailure(t *testing.T) {
+	ctx := context.Background()
+	store := newRecordingStore()
+	uc := NewDataPointUseCase(store, nil, nil, nil, dedup.New(10, dedup.ActionSkip), "test")
+
+	now := time.Now()
+	stored, failed := line(t, now, 1.5), line(t, now.Add(time.Second), 2.5)
+	_, err := uc.Push(ctx, strings.NewReader(stored))
+	require.NoError(t, err)
+
+	store.err = errors.New("connection refused")
+	_, err = uc.Push(ctx, strings.NewReader(failed))
+	require.ErrorIs(t, err, store.err)
+
+	point := dto.DataPoint{Time: now.Add(2 * time.Second), Value: 3.5, Source: "sensor"}
+	entry, ok := uc.classify(ctx, point, nil)
+	require.True(t, ok)
+	require.ErrorIs(t, uc.store(ctx, entry), store.err)
+
+	store.err = nil
+	result, err := uc.Push(ctx, strings.NewReader(stored+failed))
+	require.NoError(t, err)
+	assert.Equal(t, dto.IngestResult{Accepted: 1, Duplicates: 1}, result)
+
+	entry, ok = uc.classify(ctx, point, nil)
+	require.True(t, ok)
+	assert.False(t, entry.Duplicate)
+	require.NoError(t, uc.store(ctx, entry))
+
+	assert.Equal(t, map[string][]float64{"accepted": {1.5, 2.5, 3.5}}, store.written)
+}
@@ -64,6 +64,11 @@ func (bi BuildInfo) Info() BuildInfo {
 	}
 }
 
+// Version returns the tag and the revision of the build as a single string, e.g. "v1.2.0+4f242ae".
+func (bi BuildInfo) Version() string {
+	return bi.Tag + "+" + bi.Revision
+}
+
 // String returns a formatted string representation of the BuildInfo object.
 func (bi BuildInfo) String() string {
 	return fmt.Sprintf("Tag: %s, Revision: %s, BuildDate: %s, BuildHost: %s, BuildUser: %s, IsTaint: %s, GoArch: %s, GoOS: %s, GoVersion: %s",